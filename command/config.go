@@ -0,0 +1,371 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/kidonchu/gitcli/dbutil"
+	"github.com/kidonchu/gitcli/gitutil"
+)
+
+// hiddenSecret is shown instead of secrets in config listings
+const hiddenSecret = "********"
+
+// CmdConfigList lists `story.*` config that is set, or every known key with --all
+func CmdConfigList(c *cli.Context) {
+
+	entries, err := storyConfigEntries()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var unknown []string
+	for _, name := range sortedKeys(entries) {
+		key, err := gitutil.LookupConfigKey(name)
+		if err != nil {
+			unknown = append(unknown, name)
+			continue
+		}
+		fmt.Printf("%s=%s\n", name, displayConfigValue(key, entries[name]))
+	}
+
+	if c.Bool("all") {
+		fmt.Println("\nKnown config:")
+		for _, key := range gitutil.ConfigSchema {
+			fmt.Printf("%s (%s)\n\t%s\n", key.Name, configTypeName(key), key.Usage)
+		}
+	}
+
+	if len(unknown) > 0 {
+		fmt.Println("\nUnknown config, not read by gitcli:")
+		for _, name := range unknown {
+			fmt.Printf("%s=%s\n", name, entries[name])
+		}
+	}
+}
+
+// CmdConfigShow prints effective value of every `story.*` config, and with --origin
+// where it comes from: system, xdg, global or local git config, or shared config file
+func CmdConfigShow(c *cli.Context) {
+
+	values, err := gitutil.ConfigValues(`^story\.`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, value := range values {
+		display := value.Value
+		if key, err := gitutil.LookupConfigKey(value.Name); err == nil {
+			display = displayConfigValue(key, value.Value)
+		}
+		if c.Bool("origin") {
+			fmt.Fprintf(w, "%s\t%s\t%s=%s\n", value.Origin, value.File, value.Name, display)
+		} else {
+			fmt.Fprintf(w, "%s=%s\n", value.Name, display)
+		}
+	}
+	w.Flush()
+}
+
+// CmdConfigGet prints value of a config key, secrets resolved the way gitcli reads them
+func CmdConfigGet(c *cli.Context) {
+
+	name := c.Args().First()
+	key, err := gitutil.LookupConfigKey(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var value string
+	if key.Secret {
+		value, err = gitutil.ConfigSecret(name)
+		if err == nil && value == "" {
+			err = fmt.Errorf("No result found in git config files for `%s`", name)
+		}
+	} else {
+		value, err = gitutil.ConfigString(name)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(value)
+}
+
+// CmdConfigSet sets a config key after checking its value fits the key
+func CmdConfigSet(c *cli.Context) {
+
+	if len(c.Args()) != 2 {
+		log.Fatal("Usage: gitcli config set KEY VALUE")
+	}
+	name, value := c.Args().Get(0), c.Args().Get(1)
+
+	key, err := gitutil.LookupConfigKey(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := key.Validate(value); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := gitutil.SetConfigString(name, value); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// CmdConfigUnset removes a config key. Unknown `story.*` keys can be removed too,
+// so that leftovers reported by list and doctor can be cleaned up.
+func CmdConfigUnset(c *cli.Context) {
+
+	name := c.Args().First()
+	if !strings.HasPrefix(strings.ToLower(name), "story.") {
+		log.Fatalf("Config `%s` is not a gitcli config", name)
+	}
+
+	if err := gitutil.DeleteConfig(name); err != nil {
+		log.Fatal(err)
+	}
+
+	// value from shared config takes over
+	values, _ := gitutil.ConfigValues("(?i)^" + regexp.QuoteMeta(name) + "$")
+	for _, value := range values {
+		fmt.Printf("`%s` is still set to `%s` in `%s`\n", name, value.Value, value.File)
+	}
+}
+
+// CmdConfigDoctor checks that config is valid and what it points at is there:
+// values fit their keys, remotes exist, SSH keys are readable and database hosts answer
+func CmdConfigDoctor(c *cli.Context) {
+
+	d := &doctor{}
+
+	d.checkSharedConfig()
+	d.checkValues()
+	d.checkRemotes()
+	d.checkSSHKeys()
+	d.checkDbHosts()
+
+	if d.failures > 0 {
+		fmt.Printf("\n%d problems found\n", d.failures)
+		os.Exit(1)
+	}
+	fmt.Println("\nNo problems found")
+}
+
+// doctor prints results of config checks and counts failing ones
+type doctor struct {
+	failures int
+}
+
+func (d *doctor) ok(format string, args ...interface{}) {
+	fmt.Printf("  ok    "+format+"\n", args...)
+}
+
+func (d *doctor) warn(format string, args ...interface{}) {
+	fmt.Printf("  warn  "+format+"\n", args...)
+}
+
+func (d *doctor) fail(err error) {
+	d.failures++
+	fmt.Printf("  FAIL  %s\n", strings.Replace(strings.TrimSpace(err.Error()), "\n", "\n        ", -1))
+}
+
+// checkSharedConfig checks that shared config file of repository can be read
+func (d *doctor) checkSharedConfig() {
+
+	fmt.Println("Shared config:")
+
+	repo, err := gitutil.OpenRepo()
+	if err != nil || repo.IsBare() {
+		d.ok("No shared config outside of working tree")
+		return
+	}
+
+	shared, err := gitutil.ReadSharedConfig(repo.Workdir())
+	if err != nil {
+		d.fail(err)
+		return
+	}
+	if shared == nil {
+		d.ok("No %s in `%s`", strings.Join(gitutil.SharedConfigFiles, ", "), repo.Workdir())
+		return
+	}
+
+	d.ok("%s is read", shared.Path)
+	for _, name := range shared.Ignored {
+		d.warn("%s is ignored, it belongs to personal git config", name)
+	}
+}
+
+// checkValues checks every `story.*` value against schema, which also compiles regexes
+func (d *doctor) checkValues() {
+
+	fmt.Println("Config values:")
+
+	entries, err := storyConfigEntries()
+	if err != nil {
+		d.fail(err)
+		return
+	}
+
+	for _, name := range sortedKeys(entries) {
+		key, err := gitutil.LookupConfigKey(name)
+		if err != nil {
+			d.warn("%s is not read by gitcli", name)
+			continue
+		}
+		if err := key.Validate(entries[name]); err != nil {
+			d.fail(err)
+			continue
+		}
+		d.ok("%s=%s", name, displayConfigValue(key, entries[name]))
+	}
+}
+
+// checkRemotes checks that target remote and remotes of every source exist
+func (d *doctor) checkRemotes() {
+
+	fmt.Println("Remotes:")
+
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		d.fail(err)
+		return
+	}
+
+	checkRemote := func(name, configName string) {
+		if _, err := gitutil.GetRemote(repo, name); err != nil {
+			d.fail(fmt.Errorf("Remote `%s` in `%s` does not exist", name, configName))
+			return
+		}
+		d.ok("%s: remote `%s` exists", configName, name)
+	}
+
+	if target, _ := gitutil.ConfigString("story.remote.target"); target != "" {
+		checkRemote(target, "story.remote.target")
+	} else {
+		d.warn("story.remote.target is not set, stories cannot be pushed")
+	}
+
+	sources, _ := gitutil.ConfigEntries(`^story\.source\.`)
+	if len(sources) == 0 {
+		d.warn("No story.source.* is set, stories need --source")
+	}
+	for _, name := range sortedKeys(sources) {
+		remoteName, branchName := gitutil.SplitSource(sources[name])
+		if branchName == "" {
+			d.fail(fmt.Errorf("Source `%s` in `%s` is not in `<remote>/<branch>` format", sources[name], name))
+			continue
+		}
+		checkRemote(remoteName, name)
+	}
+}
+
+// checkSSHKeys checks that configured SSH keys and known_hosts file are readable
+func (d *doctor) checkSSHKeys() {
+
+	fmt.Println("SSH:")
+
+	checked := false
+	for _, name := range []string{"story.ssh.privatekey", "story.ssh.publickey", "story.ssh.knownhosts"} {
+		path, _ := gitutil.ConfigString(name)
+		if path == "" {
+			continue
+		}
+		checked = true
+
+		f, err := os.Open(path)
+		if err != nil {
+			d.fail(fmt.Errorf("Unable to read `%s` in `%s`\n%+v", path, name, err))
+			continue
+		}
+		f.Close()
+		d.ok("%s: `%s` is readable", name, path)
+	}
+
+	if !checked {
+		d.ok("No SSH keys configured, ssh-agent and keys in ~/.ssh are used")
+	}
+}
+
+// checkDbHosts checks that every configured database host answers
+func (d *doctor) checkDbHosts() {
+
+	fmt.Println("Database hosts:")
+
+	configs, err := dbHostConfigs()
+	if err != nil {
+		d.fail(err)
+		return
+	}
+	if len(configs) == 0 {
+		d.ok("No database hosts configured")
+		return
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	hosts, err := openDbHosts(ctx)
+	defer closeDbHosts(hosts)
+
+	for _, host := range hosts {
+		d.ok("%s answers", host.Name)
+	}
+
+	errs, ok := err.(dbutil.HostErrors)
+	if err != nil && !ok {
+		d.fail(err)
+		return
+	}
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d.fail(fmt.Errorf("%s: %s", name, errs[name]))
+	}
+}
+
+// storyConfigEntries returns every `story.*` config that is set
+func storyConfigEntries() (map[string]string, error) {
+	return gitutil.ConfigEntries(`^story\.`)
+}
+
+// displayConfigValue hides secrets
+func displayConfigValue(key gitutil.ConfigKey, value string) string {
+	if key.Secret && value != "" {
+		return hiddenSecret
+	}
+	return value
+}
+
+// configTypeName describes type of key, with allowed values of enums
+func configTypeName(key gitutil.ConfigKey) string {
+	name := key.Type.String()
+	if key.Type == gitutil.ConfigTypeEnum {
+		name += ": " + strings.Join(key.Values, ", ")
+	}
+	if key.Secret {
+		name += ", secret"
+	}
+	return name
+}
+
+// sortedKeys returns config names in order
+func sortedKeys(entries map[string]string) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
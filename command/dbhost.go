@@ -0,0 +1,220 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kidonchu/gitcli/dbutil"
+	"github.com/kidonchu/gitcli/gitutil"
+)
+
+// defaultDbRetries is how many more times connecting to database host is tried
+const defaultDbRetries = 2
+
+// defaultDbHost is the name of database host configured in `story.hosteddb.*`
+const defaultDbHost = "default"
+
+// dbHostConfig tells where config of a database host is
+type dbHostConfig struct {
+	name   string
+	prefix string
+}
+
+// key returns full config name of given key of host
+func (c dbHostConfig) key(key string) string {
+	return c.prefix + "." + key
+}
+
+// storyDb is a story database on a named host
+type storyDb struct {
+	host string
+	name string
+}
+
+// parseStoryDb reads database recorded as `<host>:<name>`, or just `<name>` on default host
+func parseStoryDb(recorded string) storyDb {
+	if i := strings.Index(recorded, ":"); i > -1 {
+		return storyDb{host: recorded[:i], name: recorded[i+1:]}
+	}
+	return storyDb{host: defaultDbHost, name: recorded}
+}
+
+func (db storyDb) String() string {
+	if db.host == defaultDbHost {
+		return db.name
+	}
+	return db.host + ":" + db.name
+}
+
+// dbHostConfigs lists database hosts, the one in `story.hosteddb.*` followed by
+// every `story.db.<name>.*` section in name order.
+// `story.db.default.*` is refused along with `story.hosteddb.*`, as both would be named default.
+func dbHostConfigs() ([]dbHostConfig, error) {
+
+	var configs []dbHostConfig
+
+	legacy, _ := gitutil.ConfigEntries(`^story\.hosteddb\.`)
+	if len(legacy) > 0 {
+		configs = append(configs, dbHostConfig{name: defaultDbHost, prefix: "story.hosteddb"})
+	}
+
+	entries, _ := gitutil.ConfigEntries(`^story\.db\.`)
+	seen := make(map[string]bool)
+	var names []string
+	for key := range entries {
+		rest := strings.TrimPrefix(key, "story.db.")
+		i := strings.LastIndex(rest, ".")
+		if i < 1 {
+			// settings for every host, like `story.db.protected`
+			continue
+		}
+		if name := rest[:i]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == defaultDbHost && len(legacy) > 0 {
+			return nil, fmt.Errorf(
+				"Database host `%s` is configured in both `story.hosteddb.*` and `story.db.%s.*`. "+
+					"Move settings of one of them to another `story.db.<name>.*`", name, name)
+		}
+		configs = append(configs, dbHostConfig{name: name, prefix: "story.db." + name})
+	}
+
+	return configs, nil
+}
+
+// dbHostOptions reads connection settings of database host in given config, password included
+func dbHostOptions(config dbHostConfig) (dbutil.Options, error) {
+
+	opts := dbutil.Options{}
+	opts.Driver, _ = gitutil.ConfigString(config.key("driver"))
+	opts.Host, _ = gitutil.ConfigString(config.key("host"))
+	opts.Port, _ = gitutil.ConfigInt32(config.key("port"))
+	opts.User, _ = gitutil.ConfigString(config.key("user"))
+	opts.SSLMode, _ = gitutil.ConfigString(config.key("sslmode"))
+	opts.Dir, _ = gitutil.ConfigString(config.key("dir"))
+
+	// databases protected on every host, and on this host only
+	protected, _ := gitutil.ConfigString("story.db.protected")
+	hostProtected, _ := gitutil.ConfigString(config.key("protected"))
+	opts.Protected = append(splitList(protected), splitList(hostProtected)...)
+
+	// host can take longer to answer than others
+	timeout, err := gitutil.ConfigInt32(config.key("timeout"))
+	if err != nil {
+		timeout, err = gitutil.ConfigInt32("story.db.timeout")
+	}
+	if err == nil {
+		opts.Timeout = time.Duration(timeout) * time.Second
+	}
+	opts.Retries = defaultDbRetries
+	if retries, err := gitutil.ConfigInt32("story.db.retries"); err == nil {
+		opts.Retries = int(retries)
+	}
+
+	pass, err := gitutil.ConfigSecret(config.key("pass"))
+	if err != nil {
+		return dbutil.Options{}, err
+	}
+	opts.Pass = pass
+
+	return opts, nil
+}
+
+// openDbHost returns driver for database host in given config, once the host answers
+func openDbHost(ctx context.Context, config dbHostConfig) (dbutil.Host, error) {
+
+	opts, err := dbHostOptions(config)
+	if err != nil {
+		return dbutil.Host{}, err
+	}
+
+	driver, err := dbutil.Open(ctx, opts)
+	if err != nil {
+		return dbutil.Host{}, err
+	}
+
+	return dbutil.Host{Name: config.name, Driver: driver}, nil
+}
+
+// openDbHosts returns drivers for every configured database host, connecting concurrently
+// so that unreachable hosts do not add up their timeouts.
+// Passwords are read one host at a time first, since secret commands may prompt on terminal.
+// Hosts that could not be opened are reported in error, the rest are returned.
+func openDbHosts(ctx context.Context) ([]dbutil.Host, error) {
+
+	configs, err := dbHostConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	options := make([]dbutil.Options, len(configs))
+	errs := make([]error, len(configs))
+	for i, config := range configs {
+		options[i], errs[i] = dbHostOptions(config)
+	}
+
+	opened := make([]dbutil.Driver, len(configs))
+	var wg sync.WaitGroup
+	for i := range configs {
+		if errs[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			opened[i], errs[i] = dbutil.Open(ctx, options[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var hosts []dbutil.Host
+	hostErrs := make(dbutil.HostErrors)
+	for i, config := range configs {
+		if errs[i] != nil {
+			hostErrs[config.name] = errs[i]
+			continue
+		}
+		hosts = append(hosts, dbutil.Host{Name: config.name, Driver: opened[i]})
+	}
+
+	if len(hostErrs) > 0 {
+		return hosts, hostErrs
+	}
+
+	return hosts, nil
+}
+
+// reportSkippedDbs tells which database hosts were left out and why,
+// so that failing hosts are not mistaken for having no databases
+func reportSkippedDbs(err error) {
+
+	errs, ok := err.(dbutil.HostErrors)
+	if !ok {
+		fmt.Printf("Databases skipped: %v\n", err)
+		return
+	}
+
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("Databases on `%s` skipped: %v\n", name, strings.TrimSpace(errs[name].Error()))
+	}
+}
+
+func closeDbHosts(hosts []dbutil.Host) {
+	for _, host := range hosts {
+		host.Driver.Close()
+	}
+}
@@ -0,0 +1,31 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kidonchu/gitcli/gitutil"
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestDbHostConfigsDefaultTwice(t *testing.T) {
+
+	testutil.CheckFatal(t, gitutil.SetConfigString("story.db.default.host", "10.0.0.2"))
+	defer gitutil.DeleteConfig("story.db.default.host")
+	testutil.CheckFatal(t, gitutil.SetConfigString("story.db.analytics.host", "10.0.0.3"))
+	defer gitutil.DeleteConfig("story.db.analytics.host")
+
+	configs, err := dbHostConfigs()
+	testutil.CheckFatal(t, err)
+	if len(configs) != 2 || configs[0].name != "analytics" || configs[1].prefix != "story.db.default" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `analytics` and `default` hosts but got %+v", configs))
+	}
+
+	// legacy host is named default as well
+	testutil.CheckFatal(t, gitutil.SetConfigString("story.hosteddb.host", "10.0.0.1"))
+	defer gitutil.DeleteConfig("story.hosteddb.host")
+
+	if configs, err := dbHostConfigs(); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected two `default` hosts to be refused but got %+v", configs))
+	}
+}
@@ -0,0 +1,465 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/codegangsta/cli"
+	"github.com/kidonchu/gitcli/dbutil"
+	"github.com/kidonchu/gitcli/gitutil"
+)
+
+// CmdDbList lists databases of current story by host
+func CmdDbList(c *cli.Context) {
+
+	branchName, dbs := currentStoryDbs()
+	if len(dbs) == 0 {
+		fmt.Printf("Story `%s` has no databases. Run `gitcli story db create` to create them\n", branchName)
+		return
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	for _, db := range dbs {
+		status := ""
+		if exists, err := storyDbExists(ctx, db); err != nil {
+			status = fmt.Sprintf(" (unknown: %v)", err)
+		} else if !exists {
+			status = " (missing)"
+		}
+		fmt.Printf("%s\t%s%s\n", db.host, db.name, status)
+	}
+}
+
+// CmdDbCreate creates databases of current story that do not exist yet
+func CmdDbCreate(c *cli.Context) {
+
+	branchName, err := currentBranchName()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbs, err := storyDbNames(branchName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	var existing, missing []storyDb
+	for _, db := range dbs {
+		exists, err := storyDbExists(ctx, db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if exists {
+			fmt.Printf("Database `%s` already exists\n", db)
+			existing = append(existing, db)
+			continue
+		}
+		missing = append(missing, db)
+	}
+
+	created, err := provisionStoryDbs(ctx, missing)
+	recordStoryDbs(branchName, append(existing, created...))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// CmdDbDrop drops databases of current story, or only the one given
+func CmdDbDrop(c *cli.Context) {
+
+	branchName, dbs := currentStoryDbs()
+	if c.Args().Present() {
+		db, err := pickStoryDb(dbs, c.Args().First())
+		if err != nil {
+			log.Fatal(err)
+		}
+		dbs = []storyDb{db}
+	}
+	if len(dbs) == 0 {
+		fmt.Println("Nothing to drop")
+		return
+	}
+
+	for _, db := range dbs {
+		fmt.Printf("* %s\n", db)
+	}
+	answer := GetUserInput("Drop above databases? (nY): ")
+	if answer != "Y" {
+		return
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	var dropped []storyDb
+	for _, db := range dbs {
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, remaining databases were not dropped")
+			break
+		}
+		err := withDbHost(ctx, db.host, func(driver dbutil.Driver) error {
+			fmt.Printf("Deleting database: `%s`...\n", db)
+			return driver.Drop(ctx, db.name)
+		})
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		dropped = append(dropped, db)
+	}
+
+	forgetStoryDbs(branchName, dropped)
+}
+
+// CmdDbClone copies databases of another story into databases of current story.
+// Databases the other story has recorded are copied, or ones named by templates
+// when it has none. Existing databases of current story are dropped first only when confirmed.
+func CmdDbClone(c *cli.Context) {
+
+	from := c.String("source")
+	if from == "" {
+		log.Fatal("Story to clone databases from is not specified")
+	}
+
+	branchName, err := currentBranchName()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	targets, err := storyDbNames(branchName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sources, err := cloneSourceDbs(from)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// databases line up by position, the way templates of each host list them
+	if len(sources) != len(targets) {
+		log.Fatalf("Story `%s` has %d databases %v, but current story needs %d %v", from, len(sources), sources, len(targets), targets)
+	}
+	for i, target := range targets {
+		if sources[i].host != target.host {
+			log.Fatalf("Database `%s` of `%s` is not on the same host as `%s`", sources[i], from, target)
+		}
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	var existing []storyDb
+	for _, target := range targets {
+		exists, err := storyDbExists(ctx, target)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if exists {
+			existing = append(existing, target)
+		}
+	}
+	if len(existing) > 0 {
+		for _, db := range existing {
+			fmt.Printf("* %s\n", db)
+		}
+		answer := GetUserInput("Above databases already exist. Drop them before cloning? (nY): ")
+		if answer != "Y" {
+			log.Fatal("Nothing cloned. Run `gitcli story db drop` to drop databases of current story first")
+		}
+		for _, db := range existing {
+			err := withDbHost(ctx, db.host, func(driver dbutil.Driver) error {
+				fmt.Printf("Deleting database: `%s`...\n", db)
+				return driver.Drop(ctx, db.name)
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	var cloned []storyDb
+	for i, target := range targets {
+		source := sources[i]
+		err := withDbHost(ctx, target.host, func(driver dbutil.Driver) error {
+			fmt.Printf("Cloning database `%s` into `%s`\n", source, target)
+			return driver.Clone(ctx, source.name, target.name)
+		})
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		cloned = append(cloned, target)
+	}
+
+	recordStoryDbs(branchName, cloned)
+}
+
+// cloneSourceDbs returns databases recorded for story, or ones named by templates when there are none
+func cloneSourceDbs(branchName string) ([]storyDb, error) {
+
+	recorded, _ := gitutil.GetStoryDbs(branchName)
+	if len(recorded) == 0 {
+		return storyDbNames(branchName)
+	}
+
+	var dbs []storyDb
+	for _, db := range recorded {
+		dbs = append(dbs, parseStoryDb(db))
+	}
+
+	return dbs, nil
+}
+
+// CmdDbDump writes SQL dump of story database to stdout or file
+func CmdDbDump(c *cli.Context) {
+
+	_, dbs := currentStoryDbs()
+	db, err := pickStoryDb(dbs, c.Args().First())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var out io.Writer = os.Stdout
+	if path := c.String("file"); path != "" {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	err = withDbHost(ctx, db.host, func(driver dbutil.Driver) error {
+		return driver.Dump(ctx, db.name, out)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// CmdDbRestore runs SQL dump from stdin or file against story database
+func CmdDbRestore(c *cli.Context) {
+
+	_, dbs := currentStoryDbs()
+	db, err := pickStoryDb(dbs, c.Args().First())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var in io.Reader = os.Stdin
+	if path := c.String("file"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	err = withDbHost(ctx, db.host, func(driver dbutil.Driver) error {
+		return driver.Restore(ctx, db.name, in)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// CmdDbConnect opens native client of database server connected to story database
+func CmdDbConnect(c *cli.Context) {
+
+	_, dbs := currentStoryDbs()
+	db, err := pickStoryDb(dbs, c.Args().First())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := interruptContext()
+	var cmd *exec.Cmd
+	err = withDbHost(ctx, db.host, func(driver dbutil.Driver) error {
+		cmd, err = driver.Command(db.name)
+		return err
+	})
+	cancel()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Ctrl-C belongs to the client, which gets it along with gitcli.
+	// Catching it, rather than ignoring it, keeps it working in the client.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		// exit the way the client did
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				os.Exit(status.ExitStatus())
+			}
+		}
+		log.Fatal(err)
+	}
+}
+
+// currentBranchName returns name of current story
+func currentBranchName() (string, error) {
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		return "", err
+	}
+	return gitutil.CurrentBranchName(repo)
+}
+
+// currentStoryDbs returns current story with databases recorded for it
+func currentStoryDbs() (string, []storyDb) {
+
+	branchName, err := currentBranchName()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	recorded, _ := gitutil.GetStoryDbs(branchName)
+	var dbs []storyDb
+	for _, db := range recorded {
+		dbs = append(dbs, parseStoryDb(db))
+	}
+
+	return branchName, dbs
+}
+
+// pickStoryDb finds story database by `<host>:<name>` or name,
+// which can be left out when story has a single database
+func pickStoryDb(dbs []storyDb, name string) (storyDb, error) {
+
+	if name == "" {
+		if len(dbs) == 1 {
+			return dbs[0], nil
+		}
+		if len(dbs) == 0 {
+			return storyDb{}, fmt.Errorf("Current story has no databases")
+		}
+		return storyDb{}, fmt.Errorf("Current story has %d databases, specify one of %v", len(dbs), dbs)
+	}
+
+	var matched []storyDb
+	for _, db := range dbs {
+		if db.String() == name || db.name == name {
+			matched = append(matched, db)
+		}
+	}
+	if len(matched) != 1 {
+		return storyDb{}, fmt.Errorf("`%s` is not a database of current story, specify one of %v", name, dbs)
+	}
+
+	return matched[0], nil
+}
+
+// withDbHost runs fn with driver of named database host
+func withDbHost(ctx context.Context, name string, fn func(dbutil.Driver) error) error {
+
+	configs, err := dbHostConfigs()
+	if err != nil {
+		return err
+	}
+
+	for _, config := range configs {
+		if config.name != name {
+			continue
+		}
+		host, err := openDbHost(ctx, config)
+		if err != nil {
+			return err
+		}
+		defer host.Driver.Close()
+		return fn(host.Driver)
+	}
+
+	return fmt.Errorf("Database host `%s` is not configured", name)
+}
+
+// storyDbExists tells whether story database is on its host
+func storyDbExists(ctx context.Context, db storyDb) (bool, error) {
+
+	exists := false
+	err := withDbHost(ctx, db.host, func(driver dbutil.Driver) error {
+		names, err := driver.List(ctx, "")
+		for _, name := range names {
+			if name == db.name {
+				exists = true
+			}
+		}
+		return err
+	})
+
+	return exists, err
+}
+
+// recordStoryDbs adds databases to the ones recorded for story
+func recordStoryDbs(branchName string, dbs []storyDb) {
+
+	recorded, _ := gitutil.GetStoryDbs(branchName)
+	seen := make(map[string]bool)
+	for _, db := range recorded {
+		seen[db] = true
+	}
+	for _, db := range dbs {
+		if !seen[db.String()] {
+			seen[db.String()] = true
+			recorded = append(recorded, db.String())
+		}
+	}
+	if len(recorded) == 0 {
+		return
+	}
+
+	if err := gitutil.SetStoryDbs(branchName, recorded); err != nil {
+		log.Println(err)
+	}
+}
+
+// forgetStoryDbs removes databases from the ones recorded for story
+func forgetStoryDbs(branchName string, dbs []storyDb) {
+
+	forget := make(map[string]bool)
+	for _, db := range dbs {
+		forget[db.String()] = true
+	}
+
+	recorded, _ := gitutil.GetStoryDbs(branchName)
+	var remaining []string
+	for _, db := range recorded {
+		if !forget[parseStoryDb(db).String()] {
+			remaining = append(remaining, db)
+		}
+	}
+
+	var err error
+	if len(remaining) == 0 {
+		err = gitutil.DeleteConfig(fmt.Sprintf("branch.%s.storydbs", branchName))
+	} else {
+		err = gitutil.SetStoryDbs(branchName, remaining)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
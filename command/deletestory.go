@@ -1,31 +1,37 @@
 package command
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"log"
-	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/codegangsta/cli"
-	_ "github.com/go-sql-driver/mysql" // mysql driver
 	"github.com/kidonchu/gitcli/dbutil"
 	"github.com/kidonchu/gitcli/gitutil"
 	"github.com/libgit2/git2go"
 )
 
+// defaultDbConfirmThreshold is how many databases can be dropped without typing their names
+const defaultDbConfirmThreshold = 3
+
 // CmdDeleteStory deletes story
 // First, it deletes local and remote branchs whose name matches with the `pattern`
 // Then, it deletes the databases whose name matches with the `pattern`
+// Nothing is deleted before every choice is confirmed.
 func CmdDeleteStory(c *cli.Context) {
 
 	pattern := c.String("pattern")
+	if pattern == "" && !c.Bool("all") {
+		log.Fatal("Give part of story name with --pattern, or --all to choose among every story")
+		return
+	}
 
 	// Get repo instance
-	root, _ := os.Getwd()
-	repo, err := gitutil.GetRepo(root)
+	repo, err := gitutil.OpenRepo()
 	if err != nil {
 		log.Fatal(err)
 		return
@@ -41,27 +47,31 @@ func CmdDeleteStory(c *cli.Context) {
 	// find stashes to delete
 	stashes := gitutil.FindStashes(repo, "^.*"+pattern+".*$")
 
-	// Now handle databases
-	dbh, err := getDbConnection()
-	if err == nil {
-		defer dbh.Close()
+	// Now handle databases on every host
+	ctx, cancel := interruptContext()
+	hosts, err := openDbHosts(ctx)
+	if err != nil {
+		reportSkippedDbs(err)
 	}
+	defer closeDbHosts(hosts)
 
-	var dbs []string
-	if dbh != nil {
-		// find dbs to delete
-		dbs, _ = dbutil.FindDbs(dbh, "^.*"+pattern+".*$")
+	// find dbs to delete
+	dbs, dbsByPattern, err := findStoryDbs(ctx, hosts, branches, pattern)
+	cancel()
+	if err != nil {
+		reportSkippedDbs(err)
 	}
 
-	if len(branches) < 1 && len(stashes) < 1 && len(dbs) < 1 {
+	if len(branches) < 1 && len(stashes) < 1 && countDbs(dbs) < 1 {
 		fmt.Println("Nothing to delete")
 		return
 	}
 
-	branchesToDelete, stashesToDelete, dbsToDelete, err := getItemsToDelete(branches, stashes, dbs)
+	branchesToDelete, stashesToDelete, dbsToDelete, err := getItemsToDelete(branches, stashes, dbs, dbsByPattern)
 	if err != nil {
 		log.Fatal(err)
 	}
+	dbsToDelete = confirmDbDrops(dbsToDelete)
 
 	if len(branchesToDelete) > 0 {
 		remote, err := gitutil.GetRemote(repo, "origin")
@@ -79,37 +89,127 @@ func CmdDeleteStory(c *cli.Context) {
 		gitutil.DeleteStashes(repo, stashesToDelete)
 	}
 
-	if len(dbsToDelete) > 0 {
-		err = dbutil.Drop(dbh, dbsToDelete)
-		if err != nil {
-			fmt.Printf("%+v", err)
+	if countDbs(dbsToDelete) > 0 {
+		ctx, cancel := interruptContext()
+		defer cancel()
+		err = dbutil.DropAll(ctx, hosts, dbsToDelete)
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, remaining databases were not dropped")
+		} else if err != nil {
+			fmt.Printf("%+v\n", err)
 		}
 	}
 }
 
-func getDbConnection() (*sql.DB, error) {
-	var (
-		host, _ = gitutil.ConfigString("story.hosteddb.host")
-		port, _ = gitutil.ConfigInt32("story.hosteddb.port")
-		user, _ = gitutil.ConfigString("story.hosteddb.user")
-		pass, _ = gitutil.ConfigString("story.hosteddb.pass")
-	)
+// findStoryDbs returns databases of given stories, by host.
+// When any of the stories has recorded databases, only the recorded ones that still exist are returned.
+// Otherwise databases whose name matches pattern are returned, and byPattern tells so.
+// Without pattern, like with --all, databases are never matched by name.
+func findStoryDbs(ctx context.Context, hosts []dbutil.Host, branches gitutil.Branches, pattern string) (found map[string][]string, byPattern bool, err error) {
 
-	dbh, err := dbutil.Connect(host, port, user, pass)
-	if err != nil {
-		return nil, err
+	var recorded []storyDb
+	for _, branch := range branches {
+		name, _ := branch.Name()
+		dbs, _ := gitutil.GetStoryDbs(name)
+		for _, db := range dbs {
+			recorded = append(recorded, parseStoryDb(db))
+		}
+	}
+	if len(recorded) > 0 {
+		found, err = findExistingDbs(ctx, hosts, recorded)
+		return found, false, err
+	}
+
+	if pattern == "" {
+		return make(map[string][]string), false, nil
+	}
+	found, err = dbutil.ListAll(ctx, hosts, "^.*"+pattern+".*$")
+	return found, true, err
+}
+
+// findExistingDbs looks up every database by its exact name, returning the ones that exist by host
+func findExistingDbs(ctx context.Context, hosts []dbutil.Host, dbs []storyDb) (map[string][]string, error) {
+
+	found := make(map[string][]string)
+	errs := make(dbutil.HostErrors)
+	for _, host := range hosts {
+		for _, db := range dbs {
+			if db.host != host.Name || containsString(found[host.Name], db.name) {
+				continue
+			}
+			names, err := host.Driver.List(ctx, "^"+regexp.QuoteMeta(db.name)+"$")
+			if err != nil {
+				errs[host.Name] = err
+				break
+			}
+			if containsString(names, db.name) {
+				found[host.Name] = append(found[host.Name], db.name)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return found, errs
+	}
+	return found, nil
+}
+
+// containsString tells whether names has name
+func containsString(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmDbDrops makes user type name of every database to drop when more than
+// `story.db.confirmthreshold` (default 3) are chosen, leaving out mistyped ones
+func confirmDbDrops(dbs map[string][]string) map[string][]string {
+
+	threshold := defaultDbConfirmThreshold
+	if configured, err := gitutil.ConfigInt32("story.db.confirmthreshold"); err == nil {
+		threshold = int(configured)
 	}
-	return dbh, nil
+	if countDbs(dbs) <= threshold {
+		return dbs
+	}
+
+	fmt.Printf("%d databases are about to be dropped. Type the name of each to confirm.\n", countDbs(dbs))
+	confirmed := make(map[string][]string)
+	for host, names := range dbs {
+		for _, name := range names {
+			answer := GetUserInput(fmt.Sprintf("Drop `%s` on `%s`? Type its name: ", name, host))
+			if answer != name {
+				fmt.Printf("Keeping database `%s`\n", name)
+				continue
+			}
+			confirmed[host] = append(confirmed[host], name)
+		}
+	}
+
+	return confirmed
+}
+
+// countDbs counts databases on every host
+func countDbs(dbs map[string][]string) int {
+	count := 0
+	for _, names := range dbs {
+		count += len(names)
+	}
+	return count
 }
 
 func getItemsToDelete(
 	branches gitutil.Branches,
 	stashes map[int]*gitutil.StashInfo,
-	dbs []string,
+	dbs map[string][]string,
+	dbsByPattern bool,
 ) (
 	[]*git.Branch,
 	map[int]*gitutil.StashInfo,
-	[]string,
+	map[string][]string,
 	error,
 ) {
 
@@ -137,13 +237,32 @@ func getItemsToDelete(
 		}
 		fmt.Println("")
 	}
-	if len(dbs) > 0 {
-		sort.Strings(dbs)
-		fmt.Println("Databases:")
-		for i, database := range dbs {
-			options[optionIndex] = "database-" + strconv.Itoa(i)
+
+	// databases are grouped by host
+	var hostNames []string
+	for host, names := range dbs {
+		if len(names) > 0 {
+			hostNames = append(hostNames, host)
+		}
+	}
+	sort.Strings(hostNames)
+
+	// databases matched by name may belong to something else than the stories
+	heading := "Databases on `%s`:\n"
+	if dbsByPattern {
+		heading = "Databases on `%s` matching pattern, no story has recorded databases:\n"
+	}
+
+	var databases []storyDb
+	for _, host := range hostNames {
+		names := dbs[host]
+		sort.Strings(names)
+		fmt.Printf(heading, host)
+		for _, name := range names {
+			options[optionIndex] = "database-" + strconv.Itoa(len(databases))
+			databases = append(databases, storyDb{host: host, name: name})
 			optionIndex++
-			fmt.Printf("%d. %s\n", optionIndex, database)
+			fmt.Printf("%d. %s\n", optionIndex, name)
 		}
 		fmt.Println("")
 	}
@@ -153,7 +272,7 @@ func getItemsToDelete(
 
 	var branchesToDelete []*git.Branch
 	stashesToDelete := make(map[int]*gitutil.StashInfo)
-	var dbsToDelete []string
+	dbsToDelete := make(map[string][]string)
 
 	for _, i := range choices {
 		optionIndex, _ := strconv.Atoi(i)
@@ -167,7 +286,8 @@ func getItemsToDelete(
 		case "stash":
 			stashesToDelete[index] = stashes[index]
 		case "database":
-			dbsToDelete = append(dbsToDelete, dbs[index])
+			db := databases[index]
+			dbsToDelete[db.host] = append(dbsToDelete[db.host], db.name)
 		}
 	}
 
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/kidonchu/gitcli/gitutil"
+	git "github.com/libgit2/git2go"
+)
+
+const (
+	defaultFetchJobs    = 4
+	defaultFetchTimeout = 60 // seconds
+)
+
+// CmdFetchStory fetches remotes current story uses, or with `--all`,
+// every remote referenced by story sources and target remote
+func CmdFetchStory(c *cli.Context) {
+
+	// Get repo instance
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	prune, _ := gitutil.ConfigBool("story.fetch.prune")
+	err = fetchStoryRemotes(repo, c.Bool("all"), c.Bool("prune") || prune)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// fetchStoryRemotes fetches story remotes concurrently
+func fetchStoryRemotes(repo *git.Repository, all bool, prune bool) error {
+
+	remoteNames, err := storyRemotes(repo, all)
+	if err != nil {
+		return err
+	}
+	if len(remoteNames) == 0 {
+		fmt.Println("No remote to fetch")
+		return nil
+	}
+
+	jobs, err := gitutil.ConfigInt32("story.fetch.jobs")
+	if err != nil || jobs < 1 {
+		jobs = defaultFetchJobs
+	}
+	timeout, err := gitutil.ConfigInt32("story.fetch.timeout")
+	if err != nil || timeout < 1 {
+		timeout = defaultFetchTimeout
+	}
+
+	fmt.Printf("Fetching most recent with remotes: %v\n", remoteNames)
+	return gitutil.FetchAll(repo, remoteNames, int(jobs), time.Duration(timeout)*time.Second, prune)
+}
+
+// storyRemotes lists remotes referenced by story config that exist in repo.
+// Unless all is set, only remotes of current story's parent and target remote are listed.
+func storyRemotes(repo *git.Repository, all bool) ([]string, error) {
+
+	var sources []string
+	if all {
+		entries, err := gitutil.ConfigEntries(`^story\.source\.`)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range entries {
+			sources = append(sources, source)
+		}
+	} else {
+		branchName, err := gitutil.CurrentBranchName(repo)
+		if err != nil {
+			return nil, err
+		}
+		parent, _ := gitutil.GetStoryParent(branchName)
+		if parent == "" {
+			parent, _ = gitutil.LookupBranchSource("default", false)
+		}
+		if parent != "" {
+			sources = append(sources, parent)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var remoteNames []string
+	addRemote := func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if _, err := repo.Remotes.Lookup(name); err != nil {
+			return
+		}
+		remoteNames = append(remoteNames, name)
+	}
+
+	for _, source := range sources {
+		if gitutil.IsLocalBranch(repo, source) {
+			continue
+		}
+		addRemote(sourceRemote(source))
+	}
+
+	targetRemoteName, err := gitutil.ConfigString("story.remote.target")
+	if err != nil {
+		targetRemoteName = "origin" // default to origin
+	}
+	addRemote(targetRemoteName)
+
+	sort.Strings(remoteNames)
+	return remoteNames, nil
+}
@@ -0,0 +1,150 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/codegangsta/cli"
+	"github.com/kidonchu/gitcli/gitutil"
+	git "github.com/libgit2/git2go"
+)
+
+// CmdListStory lists local stories as trees grouped by the branch they were created from
+func CmdListStory(c *cli.Context) {
+
+	// Get repo instance
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if c.Bool("fetch") {
+		prune, _ := gitutil.ConfigBool("story.fetch.prune")
+		err = fetchStoryRemotes(repo, true, prune)
+		if err != nil {
+			// listing does not depend on it, so keep going
+			log.Println(err)
+		}
+	}
+
+	branches, err := gitutil.FindBranches(repo, "^.*"+c.String("pattern")+".*$", git.BranchLocal)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	currentBranchName, _ := gitutil.CurrentBranchName(repo)
+
+	var names []string
+	for _, b := range branches {
+		name, _ := b.Name()
+		names = append(names, name)
+	}
+
+	roots, children, cyclic := groupStories(names)
+	for _, root := range roots {
+		if root == "" {
+			fmt.Println("(no parent)")
+		} else {
+			fmt.Println(root)
+		}
+		printStoryTree(children, root, "", currentBranchName, map[string]bool{})
+		fmt.Println("")
+	}
+
+	// such stories hang off each other, so no tree reaches them
+	if len(cyclic) > 0 {
+		fmt.Println("(parents form a cycle)")
+		for i, name := range cyclic {
+			guide := "├── "
+			if i == len(cyclic)-1 {
+				guide = "└── "
+			}
+			marker := ""
+			if name == currentBranchName {
+				marker = " *"
+			}
+			parent, _ := gitutil.GetStoryParent(name)
+			fmt.Printf("%s%s%s (parent: %s)\n", guide, name, marker, parent)
+		}
+		fmt.Println("")
+	}
+}
+
+// groupStories groups stories by their parents. Stories whose parents are
+// not listed become children of roots of the trees, returned in name order.
+// Stories no root leads to, since their parents form a cycle, are returned on their own.
+func groupStories(names []string) ([]string, map[string][]string, []string) {
+
+	listed := make(map[string]bool)
+	for _, name := range names {
+		listed[name] = true
+	}
+
+	children := make(map[string][]string)
+	var roots []string
+	for name := range listed {
+		parent, _ := gitutil.GetStoryParent(name)
+		if !listed[parent] {
+			if _, ok := children[parent]; !ok {
+				roots = append(roots, parent)
+			}
+		}
+		children[parent] = append(children[parent], name)
+	}
+
+	sort.Strings(roots)
+	for _, names := range children {
+		sort.Strings(names)
+	}
+
+	reached := make(map[string]bool)
+	queue := append([]string{}, roots...)
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, name := range children[parent] {
+			if !reached[name] {
+				reached[name] = true
+				queue = append(queue, name)
+			}
+		}
+	}
+
+	var cyclic []string
+	for name := range listed {
+		if !reached[name] {
+			cyclic = append(cyclic, name)
+		}
+	}
+	sort.Strings(cyclic)
+
+	return roots, children, cyclic
+}
+
+// printStoryTree prints children of given parent recursively with tree guides
+func printStoryTree(children map[string][]string, parent string, indent string, current string, visited map[string]bool) {
+
+	names := children[parent]
+	sort.Strings(names)
+
+	for i, name := range names {
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+
+		guide, childIndent := "├── ", "│   "
+		if i == len(names)-1 {
+			guide, childIndent = "└── ", "    "
+		}
+
+		marker := ""
+		if name == current {
+			marker = " *"
+		}
+
+		fmt.Printf("%s%s%s%s\n", indent, guide, name, marker)
+		printStoryTree(children, name, indent+childIndent, current, visited)
+	}
+}
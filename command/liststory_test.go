@@ -0,0 +1,48 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestGroupStories(t *testing.T) {
+
+	base := commitFile(t, "README", "list\n", nil)
+	createStory(t, "list-a", base, "origin/master")
+	createStory(t, "list-b", base, "list-a")
+	createStory(t, "list-c", base, "list-a")
+	createStory(t, "list-d", base, "list-c")
+	createStory(t, "list-e", base, "origin/master")
+	createStory(t, "list-f", base, "list-hidden")
+	createStory(t, "list-x", base, "list-y")
+	createStory(t, "list-y", base, "list-x")
+
+	roots, children, cyclic := groupStories([]string{"list-d", "list-a", "list-f", "list-c", "list-b", "list-e", "list-y", "list-x"})
+
+	expectedRoots := []string{"list-hidden", "origin/master"}
+	if !reflect.DeepEqual(roots, expectedRoots) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected roots %v but got %v", expectedRoots, roots))
+	}
+
+	expected := map[string][]string{
+		"origin/master": {"list-a", "list-e"},
+		"list-a":        {"list-b", "list-c"},
+		"list-c":        {"list-d"},
+		// story whose parent is not listed hangs off the parent
+		"list-hidden": {"list-f"},
+		"list-x":      {"list-y"},
+		"list-y":      {"list-x"},
+	}
+	if !reflect.DeepEqual(children, expected) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected stories %v but got %v", expected, children))
+	}
+
+	// stories whose parents form a cycle are not left out
+	expectedCyclic := []string{"list-x", "list-y"}
+	if !reflect.DeepEqual(cyclic, expectedCyclic) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected stories in cycle %v but got %v", expectedCyclic, cyclic))
+	}
+}
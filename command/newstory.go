@@ -1,20 +1,57 @@
 package command
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/codegangsta/cli"
+	"github.com/kidonchu/gitcli/dbutil"
 	"github.com/kidonchu/gitcli/gitutil"
+	"github.com/kidonchu/gitcli/issueutil"
 )
 
 // CmdNewStory creates new branchName for story
 func CmdNewStory(c *cli.Context) {
 
+	rules, err := gitutil.LoadBranchNameRules()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Without title, story is named after the issue it works on
+	issueID := c.String("issue")
+	title := c.String("title")
+	var provider issueutil.Provider
+	if issueID != "" && c.String("branch") == "" && title == "" {
+		provider, err = issueProvider()
+		if err != nil {
+			log.Fatal(err)
+		}
+		issue, err := provider.GetIssue(issueID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Issue %s: %s\n", issue.ID, issue.Title)
+		issueID = issue.ID
+		title = issue.Title
+	}
+
+	// Validate branch name before anything is stashed or fetched
 	branchName := c.String("branch")
-	if branchName == "" {
-		log.Fatal("Branch to create is not specified")
+	if branchName != "" {
+		err = rules.Validate(branchName)
+	} else if title != "" {
+		branchName, err = rules.Build(gitutil.BranchNameVars{
+			Type:  c.String("type"),
+			Issue: issueID,
+			Title: title,
+		})
+	} else {
+		log.Fatal("Branch to create is not specified. Pass --branch, --title or --issue")
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	from := c.String("source")
@@ -29,13 +66,24 @@ func CmdNewStory(c *cli.Context) {
 	}
 
 	// Get repo instance
-	root, _ := os.Getwd()
-	repo, err := gitutil.GetRepo(root)
+	repo, err := gitutil.OpenRepo()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Work out story databases up front so that bad templates fail early
+	var dbs []storyDb
+	if c.Bool("db") {
+		dbs, err = storyDbNames(branchName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	fmt.Printf("* %s => %s\n", source, branchName)
+	for _, db := range dbs {
+		fmt.Printf("* database %s\n", db)
+	}
 
 	answer := GetUserInput("Proceed with above items? (nY): ")
 	if answer != "Y" {
@@ -59,16 +107,13 @@ func CmdNewStory(c *cli.Context) {
 		log.Fatal(err)
 	}
 
-	// Fetch from main repo before creating new branch
-	var remoteName string
-	remoteName, err = gitutil.ExtractRemote(source)
-	if err != nil {
-		remoteName = "origin" // default to origin
-	}
-	fmt.Printf("Fetching most recent with remote: `%s`\n", remoteName)
-	if err = gitutil.Fetch(repo, remoteName); err != nil {
-		// do not fail entire app even if fetch fails
-		log.Println(err)
+	// Fetch from main repo before creating new branch,
+	// unless the story is stacked on top of a local branch
+	if !gitutil.IsLocalBranch(repo, source) {
+		if err = gitutil.FetchSources(repo, []string{source}); err != nil {
+			// do not fail entire app even if fetch fails
+			log.Println(err)
+		}
 	}
 
 	fmt.Println("Creating new branch")
@@ -77,6 +122,32 @@ func CmdNewStory(c *cli.Context) {
 		log.Fatal(err)
 	}
 
+	// Remember where the story came from so that it can be synced later
+	err = gitutil.SetStoryParent(branchName, source)
+	if err != nil {
+		log.Println(err)
+	}
+
+	if issueID != "" {
+		if err = gitutil.SetStoryIssue(branchName, issueID); err != nil {
+			log.Println(err)
+		}
+		startIssue(provider, issueID)
+	}
+
+	// Databases are nice to have, story is usable without them
+	if len(dbs) > 0 {
+		ctx, cancel := interruptContext()
+		created, err := provisionStoryDbs(ctx, dbs)
+		cancel()
+		if err != nil {
+			log.Println(err)
+		}
+		recordStoryDbs(branchName, created)
+	}
+
+	writeStoryEnv(repo, branchName)
+
 	targetRemoteName, err := gitutil.ConfigString("story.remote.target")
 	if err != nil {
 		targetRemoteName = "origin" // default to origin
@@ -101,3 +172,125 @@ func CmdNewStory(c *cli.Context) {
 		log.Fatal(err)
 	}
 }
+
+// storyDbNames builds names of story databases from `template` of every database host,
+// which can list several comma-separated templates
+func storyDbNames(branchName string) ([]storyDb, error) {
+
+	configs, err := dbHostConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	var dbs []storyDb
+	for _, config := range configs {
+		templates, _ := gitutil.ConfigString(config.key("template"))
+		for _, template := range splitList(templates) {
+			name, err := dbutil.StoryDbName(template, branchName)
+			if err != nil {
+				return nil, err
+			}
+			dbs = append(dbs, storyDb{host: config.name, name: name})
+		}
+	}
+
+	if len(dbs) == 0 {
+		return nil, fmt.Errorf(
+			"Database template is required. Run '%s' to configure",
+			"git config story.hosteddb.template '{branch}_app'",
+		)
+	}
+
+	return dbs, nil
+}
+
+// provisionStoryDbs creates story databases, cloning seed database listed at the same
+// position in `seed` of their host, or running `seedfiles` of their host otherwise.
+// It returns databases created before any failure.
+func provisionStoryDbs(ctx context.Context, dbs []storyDb) ([]storyDb, error) {
+
+	configs, err := dbHostConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	var created []storyDb
+	for _, config := range configs {
+		var hostDbs []string
+		for _, db := range dbs {
+			if db.host == config.name {
+				hostDbs = append(hostDbs, db.name)
+			}
+		}
+		if len(hostDbs) == 0 {
+			continue
+		}
+
+		host, err := openDbHost(ctx, config)
+		if err != nil {
+			return created, err
+		}
+
+		names, err := provisionHostDbs(ctx, host.Driver, config, hostDbs)
+		host.Driver.Close()
+		for _, name := range names {
+			created = append(created, storyDb{host: config.name, name: name})
+		}
+		if err != nil {
+			return created, err
+		}
+	}
+
+	return created, nil
+}
+
+func provisionHostDbs(ctx context.Context, driver dbutil.Driver, config dbHostConfig, dbs []string) ([]string, error) {
+
+	seedDbs, _ := gitutil.ConfigString(config.key("seed"))
+	seedFiles, _ := gitutil.ConfigString(config.key("seedfiles"))
+	seeds := splitList(seedDbs)
+
+	var created []string
+	for i, db := range dbs {
+		seed := dbutil.Seed{Files: splitList(seedFiles)}
+		if len(seeds) == 1 {
+			seed.Database = seeds[0]
+		} else if i < len(seeds) {
+			seed.Database = seeds[i]
+		}
+
+		if seed.Database != "" {
+			fmt.Printf("Creating database `%s` on `%s` from `%s`\n", db, config.name, seed.Database)
+		} else {
+			fmt.Printf("Creating database `%s` on `%s`\n", db, config.name)
+		}
+		if err := dbutil.Provision(ctx, driver, db, seed); err != nil {
+			return created, err
+		}
+		created = append(created, db)
+	}
+
+	return created, nil
+}
+
+// startIssue moves issue to progress state if `story.issue.start` is set.
+// Failing to do so does not stop story from being created.
+func startIssue(provider issueutil.Provider, issueID string) {
+
+	if start, _ := gitutil.ConfigBool("story.issue.start"); !start {
+		return
+	}
+
+	if provider == nil {
+		var err error
+		if provider, err = issueProvider(); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+
+	fmt.Printf("Starting progress on issue `%s`\n", issueID)
+	if err := provider.StartProgress(issueID); err != nil {
+		log.Println(err)
+	}
+}
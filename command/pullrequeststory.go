@@ -7,64 +7,52 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
-	"os"
 	"regexp"
 	"strings"
 
 	"github.com/codegangsta/cli"
 	"github.com/kidonchu/gitcli/gitutil"
+	git "github.com/libgit2/git2go"
 	"github.com/skratchdot/open-golang/open"
 )
 
 // CmdPullRequestStory switches to another branch for story
 func CmdPullRequestStory(c *cli.Context) {
 
-	from := c.String("source")
-	source, err := gitutil.LookupBranchSource(from, true)
+	// Get repo instance
+	repo, err := gitutil.OpenRepo()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Extract source's remote and branch names
-	var baseRemoteName, baseBranchName string
-	sources := strings.Split(source, "/")
-	if len(sources) == 1 {
-		baseRemoteName = "origin"
-		baseBranchName = sources[0]
-	} else {
-		baseRemoteName = sources[0]
-		baseBranchName = sources[1]
-	}
-
-	baseRemoteURL, err := gitutil.ConfigString(fmt.Sprintf("remote.%s.url", baseRemoteName))
+	head, err := repo.Head()
 	if err != nil {
 		log.Fatal(err)
 	}
+	compareBranch := head.Branch()
 
-	// Extract base repo name
-	regex, _ := regexp.Compile(`.*:(.*)/(.*)\.git?`)
-	matched := regex.FindStringSubmatch(baseRemoteURL)
-	realRemoteName := matched[1]
-	baseRepoName := matched[2]
-
-	// Get repo instance
-	root, _ := os.Getwd()
-	repo, err := gitutil.GetRepo(root)
+	compareBranchName, err := compareBranch.Name()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	head, err := repo.Head()
+	// Extract base's remote and branch names
+	baseRemoteName, baseBranchName, err := pullRequestBase(repo, compareBranchName, c.String("source"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	compareBranch := head.Branch()
 
-	compareBranchName, err := compareBranch.Name()
+	baseRemoteURL, err := gitutil.ConfigString(fmt.Sprintf("remote.%s.url", baseRemoteName))
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Extract base repo name
+	regex, _ := regexp.Compile(`.*:(.*)/(.*)\.git?`)
+	matched := regex.FindStringSubmatch(baseRemoteURL)
+	realRemoteName := matched[1]
+	baseRepoName := matched[2]
+
 	compareRemoteName, err := gitutil.ConfigString(fmt.Sprintf("branch.%s.remote", compareBranchName))
 	if err != nil {
 		log.Fatal(err)
@@ -91,6 +79,31 @@ func CmdPullRequestStory(c *cli.Context) {
 	open.Run(prURL)
 }
 
+// pullRequestBase returns remote and branch pull request of given story is opened against.
+// Stacked story is reviewed against the local story it was created on, unless source is given.
+func pullRequestBase(repo *git.Repository, branchName string, from string) (string, string, error) {
+
+	parent, _ := gitutil.GetStoryParent(branchName)
+	if from == "" && parent != "" && gitutil.IsLocalBranch(repo, parent) {
+		remoteName, err := gitutil.ConfigString(fmt.Sprintf("branch.%s.remote", parent))
+		if err != nil {
+			return "", "", fmt.Errorf("Parent story `%s` has no upstream. Push it before opening pull request", parent)
+		}
+		return remoteName, parent, nil
+	}
+
+	source, err := gitutil.LookupBranchSource(from, true)
+	if err != nil {
+		return "", "", err
+	}
+
+	sources := strings.Split(source, "/")
+	if len(sources) == 1 {
+		return "origin", sources[0], nil
+	}
+	return sources[0], sources[1], nil
+}
+
 // createPR creates a PR and returns the URL to the PR
 func createPR(
 	owner string,
@@ -163,26 +176,30 @@ func createPR(
  * inside of brackets.
  */
 func getTitle(branch string) (string, error) {
-	curDir, _ := os.Getwd()
-	if !isGitRepo(curDir) {
-		return "", errors.New("Not a git repository")
+	msgFilename, err := gitDirFile("PR_TITLE_MESSAGE")
+	if err != nil {
+		return "", err
 	}
-
-	msgFilename := fmt.Sprintf("%s/.git/PR_TITLE_MESSAGE", curDir)
 	msg, err := GetUserInputFromEditor(msgFilename)
 	if err != nil {
 		return "", err
 	}
 
-	// get issue ticket number
-	issueID, err := extractIssueNumber(branch)
-	if err != nil {
-		return "", err
+	// get issue ticket number, preferring the one story was created for
+	issueID, _ := gitutil.GetStoryIssue(branch)
+	if issueID == "" {
+		issueID, err = extractIssueNumber(branch)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	// append issue number to the end of title
 	prefix, _ := gitutil.ConfigString("story.issuePrefix")
-	msg += fmt.Sprintf(" [%s%s]", prefix, issueID)
+	if !strings.HasPrefix(issueID, prefix) {
+		issueID = prefix + issueID
+	}
+	msg += fmt.Sprintf(" [%s]", issueID)
 
 	return msg, nil
 }
@@ -191,12 +208,10 @@ func getTitle(branch string) (string, error) {
 * getBody asks the user to type in the body of the PR.
  */
 func getBody() (string, error) {
-	curDir, _ := os.Getwd()
-	if !isGitRepo(curDir) {
-		return "", errors.New("Not a git repository")
+	msgFilename, err := gitDirFile("PR_BODY_MESSAGE")
+	if err != nil {
+		return "", err
 	}
-
-	msgFilename := fmt.Sprintf("%s/.git/PR_BODY_MESSAGE", curDir)
 	msg, err := GetUserInputFromEditor(msgFilename)
 	if err != nil {
 		return "", err
@@ -225,13 +240,16 @@ func buildRequest(req *request) (*http.Request, error) {
 		return nil, err
 	}
 
-	token, _ := gitutil.ConfigString("story.oauthtoken")
+	token, err := gitutil.ConfigSecret("story.oauthtoken")
+	if err != nil {
+		return nil, err
+	}
 	if token == "" {
 		return nil, fmt.Errorf(
-			"OAuth Token is required. Run '%s' to configure",
-			"git config story.oauthtoken <oauth_token>",
+			"OAuth Token is required. Set %s, or run '%s' to configure",
+			gitutil.SecretEnvName("story.oauthtoken"),
+			"git config story.oauthtokencmd <command_printing_token>",
 		)
-
 	}
 
 	httpreq.Header.Set("Authorization", fmt.Sprintf("token %s", token))
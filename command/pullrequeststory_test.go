@@ -0,0 +1,48 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kidonchu/gitcli/gitutil"
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestPullRequestBase(t *testing.T) {
+
+	base := commitFile(t, "README", "pull request\n", nil)
+	createStory(t, "pr-parent", base, "origin/master")
+	createStory(t, "pr-child", base, "pr-parent")
+	createStory(t, "pr-unpushed-child", base, "pr-unpushed")
+	createStory(t, "pr-unpushed", base, "origin/master")
+	testutil.CheckFatal(t, gitutil.SetConfigString("branch.pr-parent.remote", "kchu"))
+	testutil.CheckFatal(t, gitutil.SetConfigString("story.source.default", "upstream/develop"))
+	testutil.CheckFatal(t, gitutil.SetConfigString("story.source.hotfix", "upstream/release"))
+
+	cases := []struct {
+		story  string
+		from   string
+		remote string
+		branch string
+	}{
+		// stacked story is reviewed against its local parent story
+		{"pr-child", "", "kchu", "pr-parent"},
+		// unless source is given
+		{"pr-child", "hotfix", "upstream", "release"},
+		// story created from remote branch uses default source
+		{"pr-parent", "", "upstream", "develop"},
+	}
+
+	for _, c := range cases {
+		remote, branch, err := pullRequestBase(testRepo, c.story, c.from)
+		testutil.CheckFatal(t, err)
+		if remote != c.remote || branch != c.branch {
+			testutil.CheckFatal(t, fmt.Errorf("Expected base `%s/%s` of `%s` but got `%s/%s`", c.remote, c.branch, c.story, remote, branch))
+		}
+	}
+
+	// parent story has to be pushed to review against it
+	if _, _, err := pullRequestBase(testRepo, "pr-unpushed-child", ""); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected parent story without upstream to be reported"))
+	}
+}
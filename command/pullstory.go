@@ -3,49 +3,161 @@ package command
 import (
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
 	"github.com/codegangsta/cli"
 	"github.com/kidonchu/gitcli/gitutil"
+	git "github.com/libgit2/git2go"
 )
 
 // CmdPullStory pulls specified source into current branch
 func CmdPullStory(c *cli.Context) {
 
-	from := c.String("source")
-	source, err := gitutil.LookupBranchSource(from, true)
-	if err != nil {
-		log.Fatal(err)
+	var sources []string
+	if c.Bool("all-sources") {
+		var err error
+		sources, err = pullSources()
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		from := c.String("source")
+		source, err := gitutil.LookupBranchSource(from, true)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sources = []string{source}
 	}
 
 	// Get repo instance
-	root, _ := os.Getwd()
-	repo, err := gitutil.GetRepo(root)
+	repo, err := gitutil.OpenRepo()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	var remoteName string
-
-	// Extract source's remote and branch names
-	sources := strings.Split(source, "/")
-	if len(sources) == 1 {
-		remoteName = "origin"
-	} else {
-		remoteName = sources[0]
+	if gitutil.IsRebasing(repo) {
+		log.Fatal("Rebase in progress. Run `gitcli story pull continue` or `gitcli story pull abort` first")
 	}
 
 	// Fetch from repo before pulling
-	fmt.Printf("Fetching most recent with remote: `%s`\n", remoteName)
-	if err = gitutil.Fetch(repo, remoteName); err != nil {
+	var remoteSources []string
+	for _, source := range sources {
+		if !gitutil.IsLocalBranch(repo, source) {
+			remoteSources = append(remoteSources, source)
+		}
+	}
+	if err = gitutil.FetchSources(repo, remoteSources); err != nil {
 		// do not fail entire app even if fetch fails
 		log.Println(err)
 	}
 
-	fmt.Printf("Merging %s into local branch\n", source)
-	err = gitutil.Pull(repo, source)
+	// Stash all changes for current branch, if asked to
+	autostash, _ := gitutil.ConfigBool("story.pull.autostash")
+	if autostash {
+		fmt.Println("Stashing changes on current branch")
+		err = gitutil.Stash(repo)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, source := range sources {
+		err = pullSource(repo, source, c.Bool("rebase"))
+		if err != nil {
+			if autostash {
+				log.Println("Local changes were stashed before pulling. " +
+					"Run 'git stash pop' after finishing the pull to restore them.")
+			}
+			log.Fatal(pullError(err, c.Bool("rebase")))
+		}
+	}
+
+	if autostash {
+		fmt.Println("Popping stashed changes for current branch")
+		err = gitutil.PopLastStash(repo)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// CmdPullStoryContinue continues rebase stopped by conflicts
+func CmdPullStoryContinue(c *cli.Context) {
+
+	// Get repo instance
+	repo, err := gitutil.OpenRepo()
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	fmt.Println("Continuing rebase")
+	err = gitutil.RebaseContinue(repo)
+	if err != nil {
+		log.Fatal(pullError(err, true))
+	}
+}
+
+// CmdPullStoryAbort aborts rebase in progress
+func CmdPullStoryAbort(c *cli.Context) {
+
+	// Get repo instance
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = gitutil.RebaseAbort(repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// pullSources lists sources configured in `story.pull.sources` in order
+func pullSources() ([]string, error) {
+
+	configured, _ := gitutil.ConfigString("story.pull.sources")
+	if configured == "" {
+		return nil, fmt.Errorf(
+			"Sources to pull are required. Run '%s' to configure",
+			"git config story.pull.sources <source>,<source>",
+		)
+	}
+
+	var sources []string
+	for _, from := range strings.Split(configured, ",") {
+		from = strings.TrimSpace(from)
+		if from == "" {
+			continue
+		}
+		source, err := gitutil.LookupBranchSource(from, false)
+		if err != nil {
+			// if story source not found, use it as is
+			source = from
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// pullSource merges or rebases given source into current branch
+func pullSource(repo *git.Repository, source string, rebase bool) error {
+	if rebase {
+		fmt.Printf("Rebasing local branch onto %s\n", source)
+		return gitutil.Rebase(repo, source)
+	}
+	fmt.Printf("Merging %s into local branch\n", source)
+	return gitutil.Pull(repo, source)
+}
+
+// pullError adds instructions on how to move forward when pull stops on conflicts
+func pullError(err error, rebase bool) error {
+	if err != gitutil.ErrConflicts {
+		return err
+	}
+	if rebase {
+		return fmt.Errorf("%v\nRun `gitcli story resolve`, then `gitcli story pull continue`.\n"+
+			"To go back to where you were, run `gitcli story pull abort`.", err)
+	}
+	return fmt.Errorf("%v\nRun `gitcli story resolve` to resolve them and create the merge commit.", err)
 }
@@ -0,0 +1,101 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+
+	"github.com/codegangsta/cli"
+	"github.com/kidonchu/gitcli/gitutil"
+)
+
+// urlPattern finds links in messages remote prints, like the one to open pull request
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// CmdPushStory pushes current story to its remote
+func CmdPushStory(c *cli.Context) {
+
+	// Get repo instance
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		log.Fatal(err)
+	}
+	branch := head.Branch()
+	branchName, err := branch.Name()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// push where story already tracks, otherwise to target remote
+	remoteName, _ := gitutil.ConfigString(fmt.Sprintf("branch.%s.remote", branchName))
+	hasUpstream := remoteName != ""
+	if !hasUpstream {
+		remoteName, _ = gitutil.ConfigString("story.remote.target")
+	}
+	if remoteName == "" {
+		remoteName = "origin"
+	}
+
+	remote, err := gitutil.GetRemote(repo, remoteName)
+	if err != nil {
+		log.Fatalf("Unable to find remote `%s`: %+v\n", remoteName, err)
+	}
+
+	fmt.Printf("Pushing `%s` to remote `%s`\n", branchName, remoteName)
+	result, err := gitutil.PushBranch(repo, remote, branchName, gitutil.PushOptions{
+		ForceWithLease: c.Bool("force-with-lease"),
+		NoVerify:       c.Bool("no-verify"),
+	})
+	if result != nil {
+		printPushResult(result)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !hasUpstream {
+		fmt.Println("Setting upstream to remote branch")
+		err = gitutil.SetUpstream(branch, remoteName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// printPushResult shows status of every pushed ref and what remote had to say,
+// unless it was already shown while pushing
+func printPushResult(result *gitutil.PushResult) {
+
+	refs := make([]string, 0, len(result.Statuses))
+	for refname := range result.Statuses {
+		refs = append(refs, refname)
+	}
+	sort.Strings(refs)
+
+	for _, refname := range refs {
+		if status := result.Statuses[refname]; status != "" {
+			fmt.Printf("\t[rejected] %s (%s)\n", refname, status)
+		} else {
+			fmt.Printf("\t[updated] %s\n", refname)
+		}
+	}
+
+	if !result.Streamed {
+		for _, message := range result.Messages {
+			fmt.Printf("remote: %s\n", message)
+		}
+	}
+
+	// links are easy to miss among other remote messages
+	for _, message := range result.Messages {
+		for _, link := range urlPattern.FindAllString(message, -1) {
+			fmt.Printf("\n\t%s\n", link)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/kidonchu/gitcli/gitutil"
+	git "github.com/libgit2/git2go"
+)
+
+// CmdResolveStory walks through conflicts left by story pull,
+// lets user pick a version for each file and finishes the merge
+func CmdResolveStory(c *cli.Context) {
+
+	// Get repo instance
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conflicts, err := gitutil.Conflicts(repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for i, conflict := range conflicts {
+		path := gitutil.ConflictPath(conflict)
+		fmt.Printf("\n[%d/%d] Conflict in `%s`\n", i+1, len(conflicts), path)
+
+		printConflictVersion(repo, "ancestor", conflict.Ancestor)
+		printConflictVersion(repo, "ours", conflict.Our)
+		printConflictVersion(repo, "theirs", conflict.Their)
+
+		err = resolveConflict(repo, conflict)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	remaining, err := gitutil.Conflicts(repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(remaining) > 0 {
+		fmt.Printf("\n%d conflicts remaining. Run `gitcli story resolve` again when ready.\n", len(remaining))
+		return
+	}
+
+	if gitutil.IsRebasing(repo) {
+		fmt.Println("\nAll conflicts resolved. Run `gitcli story pull continue` to continue rebase.")
+		return
+	}
+
+	fmt.Println("\nAll conflicts resolved. Creating merge commit")
+	err = gitutil.CommitMerge(repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func resolveConflict(repo *git.Repository, conflict git.IndexConflict) error {
+
+	path := gitutil.ConflictPath(conflict)
+
+	for {
+		answer := GetUserInput("Keep [o]urs, [t]heirs, open [m]ergetool or [s]kip: ")
+		switch strings.ToLower(answer) {
+		case "o":
+			return gitutil.ResolveConflict(repo, conflict, gitutil.ConflictOurs)
+		case "t":
+			return gitutil.ResolveConflict(repo, conflict, gitutil.ConflictTheirs)
+		case "m":
+			cmd := exec.Command("git", "mergetool", "--", path)
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				fmt.Printf("Mergetool did not resolve `%s`: %+v\n", path, err)
+				continue
+			}
+			return gitutil.MarkResolved(repo, path)
+		case "s":
+			return nil
+		}
+	}
+}
+
+func printConflictVersion(repo *git.Repository, label string, entry *git.IndexEntry) {
+
+	fmt.Printf("----- %s -----\n", label)
+
+	if entry == nil {
+		fmt.Println("(deleted)")
+		return
+	}
+
+	blob, err := repo.LookupBlob(entry.Id)
+	if err != nil {
+		fmt.Printf("(unable to read %s: %+v)\n", entry.Id.String(), err)
+		return
+	}
+
+	contents := blob.Contents()
+	if bytes.IndexByte(contents, 0) > -1 {
+		fmt.Printf("(binary, %d bytes)\n", len(contents))
+		return
+	}
+
+	fmt.Println(strings.TrimRight(string(contents), "\n"))
+}
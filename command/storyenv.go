@@ -0,0 +1,59 @@
+package command
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kidonchu/gitcli/gitutil"
+	git "github.com/libgit2/git2go"
+)
+
+// defaultEnvFile is where story env file is written, relative to work tree
+const defaultEnvFile = ".env.story"
+
+// writeStoryEnv regenerates env file from `story.envtemplate` for given story,
+// so that local services follow the story being worked on.
+// Nothing is done unless template is configured, and failures do not stop the caller.
+func writeStoryEnv(repo *git.Repository, branchName string) {
+
+	template, _ := gitutil.ConfigString("story.envtemplate")
+	if template == "" {
+		return
+	}
+
+	envFile, _ := gitutil.ConfigString("story.envfile")
+	if envFile == "" {
+		envFile = defaultEnvFile
+	}
+
+	// relative paths are in work tree, wherever gitcli is run from
+	template, err := gitutil.StoryEnvPath(repo.Workdir(), template)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	envFile, err = gitutil.StoryEnvPath(repo.Workdir(), envFile)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	env := gitutil.StoryEnv{Branch: branchName}
+	env.Issue, _ = gitutil.GetStoryIssue(branchName)
+	env.Parent, _ = gitutil.GetStoryParent(branchName)
+	recorded, _ := gitutil.GetStoryDbs(branchName)
+	for _, db := range recorded {
+		db := parseStoryDb(db)
+		env.Dbs = append(env.Dbs, gitutil.StoryEnvDb{Host: db.host, Name: db.name})
+	}
+
+	// left in work tree, it would be stashed away on switch and collide with the next one
+	if err := gitutil.ExcludePath(repo, envFile); err != nil {
+		log.Println(err)
+	}
+
+	fmt.Printf("Writing story env to `%s`\n", envFile)
+	if err := gitutil.WriteStoryEnv(template, envFile, env); err != nil {
+		log.Println(err)
+	}
+}
@@ -3,7 +3,6 @@ package command
 import (
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 
 	"github.com/codegangsta/cli"
@@ -17,8 +16,7 @@ func CmdSwitchStory(c *cli.Context) {
 	recent := c.Bool("recent")
 
 	// Get repo instance
-	root, _ := os.Getwd()
-	repo, err := gitutil.GetRepo(root)
+	repo, err := gitutil.OpenRepo()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -133,6 +131,10 @@ func doSwitch(repo *git.Repository, branchName string) error {
 	// pop last stash if any
 	fmt.Println("Popping last stashed changes for current branch")
 	err = gitutil.PopLastStash(repo)
+
+	// services follow the story even if restoring its changes failed
+	writeStoryEnv(repo, branchName)
+
 	if err != nil {
 		return err
 	}
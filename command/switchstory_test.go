@@ -0,0 +1,53 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kidonchu/gitcli/gitutil"
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestSwitchKeepsEnvFileOutOfStash(t *testing.T) {
+
+	testutil.CheckFatal(t, gitutil.SetConfigString("user.name", "Rand Om Hacker"))
+	testutil.CheckFatal(t, gitutil.SetConfigString("user.email", "random@hacker.com"))
+	testutil.CheckFatal(t, gitutil.SetConfigString("story.envtemplate", "switch.env.tmpl"))
+	defer gitutil.DeleteConfig("story.envtemplate")
+
+	base := commitFile(t, "switch.env.tmpl", "STORY={branch}\n", nil)
+	createStory(t, "switch-a", base, "origin/master")
+	createStory(t, "switch-b", base, "origin/master")
+
+	checkoutStory(t, "switch-a")
+	writeStoryEnv(testRepo, "switch-a")
+	wipPath := filepath.Join(testRepo.Workdir(), "wip.txt")
+	testutil.CheckFatal(t, ioutil.WriteFile(wipPath, []byte("wip\n"), 0644))
+	defer os.Remove(wipPath)
+
+	checkEnv := func(branchName string) {
+		env, err := ioutil.ReadFile(filepath.Join(testRepo.Workdir(), defaultEnvFile))
+		testutil.CheckFatal(t, err)
+		if string(env) != "STORY="+branchName+"\n" {
+			testutil.CheckFatal(t, fmt.Errorf("Expected env file of `%s` but got %q", branchName, env))
+		}
+	}
+
+	testutil.CheckFatal(t, doSwitch(testRepo, "switch-b"))
+	checkEnv("switch-b")
+
+	testutil.CheckFatal(t, doSwitch(testRepo, "switch-a"))
+	checkEnv("switch-a")
+	// switch-b had nothing but the env file, which is not a change of the story
+	if stash, _ := gitutil.ConfigString("branch.switch-b.laststash"); stash != "" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected env file to be left out of stash of `switch-b`"))
+	}
+	wip, err := ioutil.ReadFile(wipPath)
+	testutil.CheckFatal(t, err)
+	if string(wip) != "wip\n" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected stashed changes of `switch-a` to be restored but got %q", wip))
+	}
+}
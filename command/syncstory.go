@@ -0,0 +1,236 @@
+package command
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/codegangsta/cli"
+	"github.com/kidonchu/gitcli/gitutil"
+	git "github.com/libgit2/git2go"
+)
+
+// CmdSyncStory brings current story up to date with the branch it was created from.
+// With `--stack`, every story in the parent chain is synced first, from the bottom up.
+func CmdSyncStory(c *cli.Context) {
+
+	// Get repo instance
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if gitutil.IsRebasing(repo) {
+		log.Fatal("Rebase in progress. Run `gitcli story pull continue` or `gitcli story pull abort` first")
+	}
+	if repo.State() != git.RepositoryStateNone {
+		log.Fatal("Merge in progress. Run `gitcli story resolve` to finish it first")
+	}
+
+	top, stopped, err := syncStories(repo, c.Bool("stack"), c.Bool("rebase"))
+	if err == gitutil.ErrConflicts {
+		log.Fatalf("%v\nStopped while syncing `%s`. Changes of `%s` are kept in its last stash.\n%s",
+			err, stopped, top, syncResumeHint(c.Bool("rebase")))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// syncStories syncs current story, or with stack every story in its parent chain, and gets
+// back to it with its changes. When stopped by conflicts, it returns the story it started from
+// and the story it stopped at, and syncing again from there picks up the stories left.
+func syncStories(repo *git.Repository, stack bool, rebase bool) (string, string, error) {
+
+	currentBranchName, err := gitutil.CurrentBranchName(repo)
+	if err != nil {
+		return "", "", err
+	}
+
+	// sync stopped by conflicts may have left us on a story below the one it was started from
+	top, resumed := currentBranchName, false
+	if recorded, _ := gitutil.GetStorySyncTop(); recorded != "" && gitutil.IsLocalBranch(repo, recorded) {
+		top, resumed = recorded, true
+	}
+
+	chain := []string{top}
+	if stack {
+		chain, err = storyChain(repo, top)
+		if err != nil {
+			return top, "", err
+		}
+	}
+	if resumed && !containsString(chain, currentBranchName) {
+		return top, "", fmt.Errorf("Sync of `%s` is in progress. Check out `%s` or a story below it to resume it", top, top)
+	}
+
+	// Fetch the remote the bottom of the stack was created from
+	rootParent, err := gitutil.GetStoryParent(chain[0])
+	if err != nil || rootParent == "" {
+		return top, "", fmt.Errorf("No parent recorded for `%s`", chain[0])
+	}
+	if !gitutil.IsLocalBranch(repo, rootParent) {
+		if err = gitutil.FetchSources(repo, []string{rootParent}); err != nil {
+			// do not fail entire app even if fetch fails
+			log.Println(err)
+		}
+	}
+
+	if resumed {
+		fmt.Printf("Resuming sync of %s\n", top)
+	} else {
+		// Stash all changes for current branch, if any
+		fmt.Println("Stashing changes on current branch")
+		err = gitutil.Stash(repo)
+		if err != nil {
+			return top, "", err
+		}
+		if err = gitutil.SetStorySyncTop(top); err != nil {
+			return top, "", err
+		}
+	}
+
+	// stories rebased below move their children's base, so remember where they were
+	tips, err := syncTips(repo, chain)
+	if err != nil {
+		return top, "", err
+	}
+
+	for _, branchName := range chain {
+		if resumed && storySynced(repo, branchName) {
+			fmt.Printf("`%s` is already synced. Skipping\n", branchName)
+			continue
+		}
+		err = syncBranch(repo, branchName, rebase, tips)
+		if err != nil {
+			return top, branchName, err
+		}
+	}
+
+	for _, branchName := range chain {
+		if err = gitutil.DeleteStorySyncTip(branchName); err != nil {
+			log.Println(err)
+		}
+	}
+	if err = gitutil.DeleteStorySyncTop(); err != nil {
+		log.Println(err)
+	}
+
+	// get back to where we were
+	currentBranchName, err = gitutil.CurrentBranchName(repo)
+	if err != nil {
+		return top, "", err
+	}
+	if currentBranchName != top {
+		fmt.Printf("Checking out %s\n", top)
+		err = gitutil.Checkout(repo, top)
+		if err != nil {
+			return top, "", err
+		}
+	}
+
+	fmt.Println("Popping last stashed changes for current branch")
+	return top, "", gitutil.PopLastStash(repo)
+}
+
+// storySynced tells whether given story already has every commit of its parent
+func storySynced(repo *git.Repository, branchName string) bool {
+	parent, err := gitutil.GetStoryParent(branchName)
+	if err != nil || parent == "" {
+		return false
+	}
+	synced, err := gitutil.IsBranchBasedOn(repo, branchName, parent)
+	return err == nil && synced
+}
+
+// storyChain walks recorded parents of given story while they are local branches
+// and returns the chain ordered from the bottom of the stack up to given story
+func storyChain(repo *git.Repository, branchName string) ([]string, error) {
+
+	chain := []string{branchName}
+	visited := map[string]bool{branchName: true}
+
+	for {
+		parent, err := gitutil.GetStoryParent(chain[0])
+		if err != nil || parent == "" || !gitutil.IsLocalBranch(repo, parent) {
+			break
+		}
+		if visited[parent] {
+			return nil, fmt.Errorf("Story parents of `%s` form a cycle at `%s`", branchName, parent)
+		}
+		visited[parent] = true
+		chain = append([]string{parent}, chain...)
+	}
+
+	return chain, nil
+}
+
+// syncTips returns where every story in chain was before syncing. They are kept in
+// branch config until sync is done, so that sync resumed after conflicts still moves
+// children from where their parents were before being rebased.
+func syncTips(repo *git.Repository, chain []string) (map[string]*git.Oid, error) {
+
+	tips := make(map[string]*git.Oid)
+	for _, branchName := range chain {
+		if recorded, err := gitutil.GetStorySyncTip(branchName); err == nil && recorded != "" {
+			tip, err := git.NewOid(recorded)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid sync tip `%s` recorded for `%s`\n%+v", recorded, branchName, err)
+			}
+			tips[branchName] = tip
+			continue
+		}
+
+		ref, err := repo.References.Lookup("refs/heads/" + branchName)
+		if err != nil {
+			return nil, err
+		}
+		if err := gitutil.SetStorySyncTip(branchName, ref.Target().String()); err != nil {
+			return nil, err
+		}
+		tips[branchName] = ref.Target()
+	}
+
+	return tips, nil
+}
+
+// syncResumeHint tells how to finish the story stopped by conflicts and sync the rest
+func syncResumeHint(rebase bool) string {
+	if rebase {
+		return "Run `gitcli story resolve`, then `gitcli story pull continue`, " +
+			"then `gitcli story sync` again with the same options to sync the rest.\n" +
+			"To go back to where the story was, run `gitcli story pull abort`."
+	}
+	return "Run `gitcli story resolve` to create the merge commit, " +
+		"then `gitcli story sync` again with the same options to sync the rest."
+}
+
+// syncBranch checks out given story and merges or rebases its parent into it.
+// When rebasing onto parent story that was itself rebased, only commits made
+// since parent's tip in `tips` are replayed.
+func syncBranch(repo *git.Repository, branchName string, rebase bool, tips map[string]*git.Oid) error {
+
+	parent, err := gitutil.GetStoryParent(branchName)
+	if err != nil || parent == "" {
+		fmt.Printf("No parent recorded for `%s`. Skipping\n", branchName)
+		return nil
+	}
+
+	currentBranchName, err := gitutil.CurrentBranchName(repo)
+	if err != nil {
+		return err
+	}
+	if currentBranchName != branchName {
+		fmt.Printf("Checking out %s\n", branchName)
+		err = gitutil.Checkout(repo, branchName)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Syncing `%s` with `%s`\n", branchName, parent)
+	if since, ok := tips[parent]; ok && rebase {
+		fmt.Printf("Rebasing local branch onto %s\n", parent)
+		return gitutil.RebaseOnto(repo, parent, since)
+	}
+	return pullSource(repo, parent, rebase)
+}
@@ -0,0 +1,236 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kidonchu/gitcli/gitutil"
+	"github.com/kidonchu/gitcli/testutil"
+	git "github.com/libgit2/git2go"
+)
+
+// testRepo is the repository tests run in, since git config is read from working directory
+var testRepo *git.Repository
+
+func TestMain(m *testing.M) {
+
+	path, err := ioutil.TempDir("", "gitcli")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	testRepo, err = git.InitRepository(path, false)
+	if err == nil {
+		err = os.Chdir(path)
+	}
+	if err != nil {
+		os.RemoveAll(path)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(path)
+	os.Exit(code)
+}
+
+// commitFile commits file on top of parent, or as a root commit without parent,
+// without moving any branch or touching working tree
+func commitFile(t *testing.T, name string, content string, parentID *git.Oid) *git.Oid {
+
+	idx, err := testRepo.Index()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, idx.Clear())
+
+	var parents []*git.Commit
+	if parentID != nil {
+		parent, err := testRepo.LookupCommit(parentID)
+		testutil.CheckFatal(t, err)
+		parentTree, err := parent.Tree()
+		testutil.CheckFatal(t, err)
+		testutil.CheckFatal(t, idx.ReadTree(parentTree))
+		parents = append(parents, parent)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(testRepo.Workdir(), name), []byte(content), 0644)
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, idx.AddByPath(name))
+	// leave working tree for checkouts to fill
+	testutil.CheckFatal(t, os.Remove(filepath.Join(testRepo.Workdir(), name)))
+	treeID, err := idx.WriteTree()
+	testutil.CheckFatal(t, err)
+	tree, err := testRepo.LookupTree(treeID)
+	testutil.CheckFatal(t, err)
+
+	sig := &git.Signature{Name: "Rand Om Hacker", Email: "random@hacker.com", When: time.Now()}
+	commitID, err := testRepo.CreateCommit("", sig, sig, "Commit "+name+"\n", tree, parents...)
+	testutil.CheckFatal(t, err)
+
+	return commitID
+}
+
+// createStory creates local branch at commit, recording parent as the branch it was created from
+func createStory(t *testing.T, name string, target *git.Oid, parent string) {
+	_, err := testRepo.References.Create("refs/heads/"+name, target, true, "")
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, gitutil.SetStoryParent(name, parent))
+}
+
+// checkoutStory checks out given local branch, discarding local changes
+func checkoutStory(t *testing.T, name string) {
+	testutil.CheckFatal(t, testRepo.SetHead("refs/heads/"+name))
+	testutil.CheckFatal(t, testRepo.CheckoutHead(&git.CheckoutOpts{Strategy: git.CheckoutForce}))
+}
+
+func TestStoryChain(t *testing.T) {
+
+	base := commitFile(t, "README", "chain\n", nil)
+	createStory(t, "chain-a", base, "origin/master")
+	createStory(t, "chain-b", base, "chain-a")
+	createStory(t, "chain-c", base, "chain-b")
+
+	chain, err := storyChain(testRepo, "chain-c")
+	testutil.CheckFatal(t, err)
+	expected := []string{"chain-a", "chain-b", "chain-c"}
+	if !reflect.DeepEqual(chain, expected) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected chain %v but got %v", expected, chain))
+	}
+
+	// parents recorded by mistake must not loop forever
+	createStory(t, "cycle-x", base, "cycle-y")
+	createStory(t, "cycle-y", base, "cycle-x")
+	if _, err := storyChain(testRepo, "cycle-x"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected cycle of story parents to be reported"))
+	}
+}
+
+func TestSyncBranchMerge(t *testing.T) {
+
+	testutil.CheckFatal(t, gitutil.SetConfigString("user.name", "Rand Om Hacker"))
+	testutil.CheckFatal(t, gitutil.SetConfigString("user.email", "random@hacker.com"))
+
+	base := commitFile(t, "README", "merge\n", nil)
+	parentTip := commitFile(t, "parent.txt", "parent\n", base)
+	childTip := commitFile(t, "child.txt", "child\n", base)
+	createStory(t, "merge-parent", parentTip, "origin/master")
+	createStory(t, "merge-child", childTip, "merge-parent")
+
+	// sync checks out the story on its own
+	checkoutStory(t, "merge-parent")
+
+	err := syncBranch(testRepo, "merge-child", false, nil)
+	testutil.CheckFatal(t, err)
+
+	head, err := testRepo.Head()
+	testutil.CheckFatal(t, err)
+	if head.Name() != "refs/heads/merge-child" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `merge-child` to be checked out but got `%s`", head.Name()))
+	}
+	commit, err := testRepo.LookupCommit(head.Target())
+	testutil.CheckFatal(t, err)
+	if commit.ParentCount() != 2 || !commit.Parent(0).Id().Equal(childTip) || !commit.Parent(1).Id().Equal(parentTip) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected merge of %s into %s", parentTip, childTip))
+	}
+}
+
+func TestSyncTipsKeptUntilDone(t *testing.T) {
+
+	base := commitFile(t, "README", "tips\n", nil)
+	oldTip := commitFile(t, "parent.txt", "old\n", base)
+	createStory(t, "tips-parent", oldTip, "origin/master")
+
+	tips, err := syncTips(testRepo, []string{"tips-parent"})
+	testutil.CheckFatal(t, err)
+	if !tips["tips-parent"].Equal(oldTip) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected tip %s but got %s", oldTip, tips["tips-parent"]))
+	}
+
+	// parent is rebased, then sync stops on conflicts and is run again
+	newTip := commitFile(t, "parent.txt", "new\n", base)
+	_, err = testRepo.References.Create("refs/heads/tips-parent", newTip, true, "")
+	testutil.CheckFatal(t, err)
+
+	tips, err = syncTips(testRepo, []string{"tips-parent"})
+	testutil.CheckFatal(t, err)
+	if !tips["tips-parent"].Equal(oldTip) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected resumed sync to use tip %s but got %s", oldTip, tips["tips-parent"]))
+	}
+
+	testutil.CheckFatal(t, gitutil.DeleteStorySyncTip("tips-parent"))
+	if tip, _ := gitutil.GetStorySyncTip("tips-parent"); tip != "" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected sync tip to be cleared but got %s", tip))
+	}
+}
+
+func TestSyncStoriesResumed(t *testing.T) {
+
+	testutil.CheckFatal(t, gitutil.SetConfigString("user.name", "Rand Om Hacker"))
+	testutil.CheckFatal(t, gitutil.SetConfigString("user.email", "random@hacker.com"))
+
+	base := commitFile(t, "README", "stack\n", nil)
+	baseTip := commitFile(t, "shared.txt", "base\n", base)
+	aTip := commitFile(t, "a.txt", "a\n", base)
+	bTip := commitFile(t, "shared.txt", "b\n", aTip)
+	cTip := commitFile(t, "c.txt", "c\n", bTip)
+	_, err := testRepo.References.Create("refs/heads/stack-base", baseTip, true, "")
+	testutil.CheckFatal(t, err)
+	createStory(t, "stack-a", aTip, "stack-base")
+	createStory(t, "stack-b", bTip, "stack-a")
+	createStory(t, "stack-c", cTip, "stack-b")
+
+	checkoutStory(t, "stack-c")
+	wipPath := filepath.Join(testRepo.Workdir(), "wip.txt")
+	testutil.CheckFatal(t, ioutil.WriteFile(wipPath, []byte("wip\n"), 0644))
+	defer os.Remove(wipPath)
+
+	// middle story conflicts with its synced parent
+	top, stopped, err := syncStories(testRepo, true, false)
+	if err != gitutil.ErrConflicts {
+		testutil.CheckFatal(t, fmt.Errorf("Expected sync to stop on conflicts but got %v", err))
+	}
+	if top != "stack-c" || stopped != "stack-b" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected sync of `stack-c` stopped at `stack-b` but got `%s` at `%s`", top, stopped))
+	}
+
+	testutil.CheckFatal(t, ioutil.WriteFile(filepath.Join(testRepo.Workdir(), "shared.txt"), []byte("resolved\n"), 0644))
+	idx, err := testRepo.Index()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, idx.AddByPath("shared.txt"))
+	testutil.CheckFatal(t, idx.Write())
+	testutil.CheckFatal(t, gitutil.CommitMerge(testRepo))
+
+	// resumed from the middle story, sync gets through the top one
+	_, _, err = syncStories(testRepo, true, false)
+	testutil.CheckFatal(t, err)
+
+	head, err := testRepo.Head()
+	testutil.CheckFatal(t, err)
+	if head.Name() != "refs/heads/stack-c" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `stack-c` to be checked out but got `%s`", head.Name()))
+	}
+	synced, err := gitutil.IsBranchBasedOn(testRepo, "stack-c", "stack-b")
+	testutil.CheckFatal(t, err)
+	if !synced {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `stack-c` to be synced with `stack-b`"))
+	}
+
+	wip, err := ioutil.ReadFile(wipPath)
+	testutil.CheckFatal(t, err)
+	if string(wip) != "wip\n" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected stashed changes of `stack-c` to be restored but got %q", wip))
+	}
+
+	if recorded, _ := gitutil.GetStorySyncTop(); recorded != "" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected sync top to be cleared but got `%s`", recorded))
+	}
+	for _, name := range []string{"stack-a", "stack-b", "stack-c"} {
+		if tip, _ := gitutil.GetStorySyncTip(name); tip != "" {
+			testutil.CheckFatal(t, fmt.Errorf("Expected sync tip of `%s` to be cleared but got %s", name, tip))
+		}
+	}
+}
@@ -2,13 +2,17 @@ package command
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"strings"
 
 	"github.com/kidonchu/gitcli/gitutil"
+	"github.com/kidonchu/gitcli/issueutil"
 	git "github.com/libgit2/git2go"
 )
 
@@ -64,11 +68,20 @@ func GetUserInputFromEditor(msgFilename string) (string, error) {
 	return strings.Trim(string(msg), " \n"), nil
 }
 
-func isGitRepo(dirPath string) bool {
-	if _, err := os.Stat(fmt.Sprintf("%s/.git", dirPath)); err != nil {
-		return false
+// sourceRemote extracts remote name from source in `<remote>/<branch>` format
+func sourceRemote(source string) string {
+	remoteName, _ := gitutil.SplitSource(source)
+	return remoteName
+}
+
+// gitDirFile returns path of file in git directory of repository, which is not
+// always `.git` of working directory, e.g. in subdirectories or with GIT_DIR
+func gitDirFile(name string) (string, error) {
+	repo, err := gitutil.OpenRepo()
+	if err != nil {
+		return "", err
 	}
-	return true
+	return filepath.Join(repo.Path(), name), nil
 }
 
 func check(e error) {
@@ -76,3 +89,68 @@ func check(e error) {
 		panic(e)
 	}
 }
+
+// splitList splits comma-separated config value, skipping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// issueProvider returns issue tracker configured in `story.issue.*`
+func issueProvider() (issueutil.Provider, error) {
+
+	config := issueutil.Config{}
+	config.Provider, _ = gitutil.ConfigString("story.issue.provider")
+	if config.Provider == "" {
+		return nil, fmt.Errorf(
+			"Issue tracker is not configured. Run '%s' to configure",
+			"git config story.issue.provider <github|gitlab|jira>",
+		)
+	}
+	config.URL, _ = gitutil.ConfigString("story.issue.url")
+	config.Repo, _ = gitutil.ConfigString("story.issue.repo")
+	config.User, _ = gitutil.ConfigString("story.issue.user")
+	config.ProgressState, _ = gitutil.ConfigString("story.issue.progress")
+
+	token, err := gitutil.ConfigSecret("story.issue.token")
+	if err != nil {
+		return nil, err
+	}
+	if token == "" && config.Provider == "github" {
+		// same token is used to open pull requests
+		token, err = gitutil.ConfigSecret("story.oauthtoken")
+		if err != nil {
+			return nil, err
+		}
+	}
+	config.Token = token
+
+	return issueutil.NewProvider(config)
+}
+
+// interruptContext returns context canceled when user hits Ctrl-C, so that long
+// operations stop cleanly instead of being killed halfway. Ctrl-C works as usual
+// again once the context is canceled, so call cancel as soon as the operation is done.
+func interruptContext() (context.Context, context.CancelFunc) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			fmt.Fprintln(os.Stderr, "\nInterrupted, stopping...")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(interrupt)
+	}()
+
+	return ctx, cancel
+}
@@ -39,14 +39,60 @@ var Commands = []cli.Command{
 				Aliases: []string{"n"},
 				Usage:   "Create a new story",
 				Action:  command.CmdNewStory,
-				Flags:   GlobalFlags,
+				Flags: append(GlobalFlags,
+					cli.StringFlag{
+						Name:  "title",
+						Usage: "`TITLE` of story to build branch name from, when --branch is not given",
+					},
+					cli.StringFlag{
+						Name:  "type",
+						Usage: "`TYPE` of story (e.g. feature, bugfix) for branch template",
+					},
+					cli.StringFlag{
+						Name:  "issue",
+						Usage: "`ISSUE` the story works on, its title is used when --branch and --title are not given",
+					},
+					cli.BoolFlag{
+						Name:  "db",
+						Usage: "If true, create story databases from `story.hosteddb.template`",
+					},
+				),
 			},
 			{
 				Name:    "delete",
 				Aliases: []string{"d"},
 				Usage:   "Delete a story and its databases",
 				Action:  command.CmdDeleteStory,
-				Flags:   GlobalFlags,
+				Flags: append(GlobalFlags, cli.BoolFlag{
+					Name:  "all",
+					Usage: "If true, offer every story for deletion when no pattern is given",
+				}),
+			},
+			{
+				Name:    "list",
+				Aliases: []string{"l"},
+				Usage:   "List stories as trees of stacked stories",
+				Action:  command.CmdListStory,
+				Flags: append(GlobalFlags, cli.BoolFlag{
+					Name:  "f,fetch",
+					Usage: "If true, fetch every story remote before listing",
+				}),
+			},
+			{
+				Name:    "fetch",
+				Aliases: []string{"f"},
+				Usage:   "Fetch remotes of current story",
+				Action:  command.CmdFetchStory,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "a,all",
+						Usage: "If true, fetch every remote used by story sources and target remote",
+					},
+					cli.BoolFlag{
+						Name:  "prune",
+						Usage: "If true, remove remote-tracking branches deleted on remote",
+					},
+				},
 			},
 			{
 				Name:    "pullrequest",
@@ -55,12 +101,133 @@ var Commands = []cli.Command{
 				Action:  command.CmdPullRequestStory,
 				Flags:   GlobalFlags,
 			},
+			{
+				Name:   "push",
+				Usage:  "Push current story to its remote",
+				Action: command.CmdPushStory,
+				Flags: append(GlobalFlags,
+					cli.BoolFlag{
+						Name:  "force-with-lease",
+						Usage: "Overwrite remote story, unless someone else has pushed to it since last fetch",
+					},
+					cli.BoolFlag{
+						Name:  "no-verify",
+						Usage: "Skip pre-push hook",
+					},
+				),
+			},
 			{
 				Name:    "pull",
 				Aliases: []string{"p"},
 				Usage:   "Pull most recent remote to current story",
 				Action:  command.CmdPullStory,
-				Flags:   GlobalFlags,
+				Flags: append(GlobalFlags,
+					cli.BoolFlag{
+						Name:  "rebase",
+						Usage: "If true, rebase current story onto source instead of merging",
+					},
+					cli.BoolFlag{
+						Name:  "all-sources",
+						Usage: "If true, pull every source in `story.pull.sources` in order. Higher priority than --source flag",
+					},
+				),
+				Subcommands: []cli.Command{
+					{
+						Name:   "continue",
+						Usage:  "Continue rebase after resolving conflicts",
+						Action: command.CmdPullStoryContinue,
+					},
+					{
+						Name:   "abort",
+						Usage:  "Abort rebase and restore current story",
+						Action: command.CmdPullStoryAbort,
+					},
+				},
+			},
+			{
+				Name:    "resolve",
+				Aliases: []string{"r"},
+				Usage:   "Resolve conflicts left by story pull one file at a time",
+				Action:  command.CmdResolveStory,
+			},
+			{
+				Name:    "sync",
+				Aliases: []string{"y"},
+				Usage:   "Sync current story with the branch it was created from",
+				Action:  command.CmdSyncStory,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "stack",
+						Usage: "If true, sync every parent story in the stack first",
+					},
+					cli.BoolFlag{
+						Name:  "rebase",
+						Usage: "If true, rebase stories onto their parents instead of merging",
+					},
+				},
+			},
+			{
+				Name:  "db",
+				Usage: "Manage databases of current story",
+				Subcommands: []cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List databases of current story",
+						Action: command.CmdDbList,
+					},
+					{
+						Name:   "create",
+						Usage:  "Create databases of current story that do not exist yet",
+						Action: command.CmdDbCreate,
+					},
+					{
+						Name:      "drop",
+						Usage:     "Drop databases of current story, or only the one given",
+						ArgsUsage: "[DATABASE]",
+						Action:    command.CmdDbDrop,
+					},
+					{
+						Name:   "clone",
+						Usage:  "Copy databases of another story into databases of current story",
+						Action: command.CmdDbClone,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:  "s,source",
+								Usage: "`STORY` to copy databases from",
+							},
+						},
+					},
+					{
+						Name:      "dump",
+						Usage:     "Write SQL dump of story database to stdout",
+						ArgsUsage: "[DATABASE]",
+						Action:    command.CmdDbDump,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:  "f,file",
+								Usage: "Write dump to `FILE` instead",
+							},
+						},
+					},
+					{
+						Name:      "restore",
+						Usage:     "Run SQL dump from stdin against story database",
+						ArgsUsage: "[DATABASE]",
+						Action:    command.CmdDbRestore,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:  "f,file",
+								Usage: "Read dump from `FILE` instead",
+							},
+						},
+					},
+					{
+						Name:      "connect",
+						Usage:     "Open database client connected to story database",
+						ArgsUsage: "[DATABASE]",
+						Action:    command.CmdDbConnect,
+					},
+				},
 			},
 			{
 				Name:    "switch",
@@ -74,6 +241,57 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:  "config",
+		Usage: "Show, change and check gitcli config",
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List gitcli config that is set",
+				Action: command.CmdConfigList,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "a,all",
+						Usage: "If true, also describe every config gitcli reads",
+					},
+				},
+			},
+			{
+				Name:   "show",
+				Usage:  "Show value of every gitcli config in effect",
+				Action: command.CmdConfigShow,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "origin",
+						Usage: "If true, show which config file every value comes from",
+					},
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "Print value of config",
+				ArgsUsage: "KEY",
+				Action:    command.CmdConfigGet,
+			},
+			{
+				Name:      "set",
+				Usage:     "Set config after checking its value",
+				ArgsUsage: "KEY VALUE",
+				Action:    command.CmdConfigSet,
+			},
+			{
+				Name:      "unset",
+				Usage:     "Remove config",
+				ArgsUsage: "KEY",
+				Action:    command.CmdConfigUnset,
+			},
+			{
+				Name:   "doctor",
+				Usage:  "Check config values, remotes, SSH keys and database hosts",
+				Action: command.CmdConfigDoctor,
+			},
+		},
+	},
 }
 
 // CommandNotFound prints out the error message if command not found
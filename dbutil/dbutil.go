@@ -1,57 +1,112 @@
 package dbutil
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"regexp"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql" // mysql driver
 )
 
-// Connect returns db instance of given db information
+// Connect returns db instance of given db information, once the server answers
 func Connect(host string, port int32, user string, pass string) (*sql.DB, error) {
+	return connectMySQL(context.Background(), Options{Host: host, Port: port, User: user, Pass: pass})
+}
+
+func connectMySQL(ctx context.Context, opts Options) (*sql.DB, error) {
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/hosted", user, pass, host, port)
+	opts = withDefaults(opts)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/hosted?timeout=%s", opts.User, opts.Pass, opts.Host, opts.Port, opts.Timeout)
 	db, err := sql.Open("mysql", dsn)
+	if err == nil {
+		err = ping(ctx, db, opts)
+		if err != nil {
+			db.Close()
+		}
+	}
 
 	if err != nil {
-		return nil, fmt.Errorf("Unable to connect to database with `%s`", dsn)
+		// do not print dsn, it contains password
+		return nil, fmt.Errorf("Unable to connect to database `%s@%s:%d`\n%+v", opts.User, opts.Host, opts.Port, err)
 	}
 
 	return db, nil
 }
 
+// ping makes sure server answers within timeout, retrying with backoff
+// since database servers of local environments are often still starting up
+func ping(ctx context.Context, db *sql.DB, opts Options) error {
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		err = db.PingContext(pingCtx)
+		cancel()
+
+		if err == nil || ctx.Err() != nil || attempt >= opts.Retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(retryDelay << uint(attempt)):
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == context.DeadlineExceeded {
+		return fmt.Errorf("No answer within %s", opts.Timeout)
+	}
+
+	return err
+}
+
 // FindDbs returns an array of pattern-matched dbs in the db connection
 func FindDbs(dbh *sql.DB, pattern string) ([]string, error) {
+	return FindDbsContext(context.Background(), dbh, pattern)
+}
 
-	var dbs []string
+// FindDbsContext is FindDbs that gives up once ctx is done
+func FindDbsContext(ctx context.Context, dbh *sql.DB, pattern string) ([]string, error) {
 
-	rows, err := dbh.Query("SHOW DATABASES")
+	var names []string
+
+	rows, err := dbh.QueryContext(ctx, "SHOW DATABASES")
 	if err != nil {
 		return nil, fmt.Errorf("Unable to get a list of databases: %+v", err)
 	}
 	defer rows.Close()
 
-	regex, _ := regexp.Compile(pattern)
 	for rows.Next() {
 		var database string
 		if err := rows.Scan(&database); err != nil {
 			return nil, fmt.Errorf("Database could not be fetched from result rows: %+v", err)
 		}
-		matched := regex.FindString(database)
-		if matched != "" {
-			dbs = append(dbs, database)
-		}
+		names = append(names, database)
 	}
 
-	return dbs, nil
+	return matchNames(names, pattern)
 }
 
-// Drop drops provided dbs
+// Drop drops provided dbs, refusing to drop system databases
 func Drop(dbh *sql.DB, dbs []string) error {
+	return DropContext(context.Background(), dbh, dbs)
+}
+
+// DropContext is Drop that stops before next db once ctx is done
+func DropContext(ctx context.Context, dbh *sql.DB, dbs []string) error {
 	for _, db := range dbs {
+		if IsProtected(db, nil) {
+			return fmt.Errorf("Database `%s` is protected", db)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		fmt.Printf("Deleting database: `%s`...\n", db)
-		_, err := dbh.Exec(fmt.Sprintf("DROP DATABASE `%s`", db))
+		_, err := dbh.ExecContext(ctx, "DROP DATABASE "+quoteMySQL(db))
 		if err != nil {
 			return fmt.Errorf("Error while deleting database `%s`: %+v", db, err)
 		}
@@ -1,15 +1,38 @@
 package dbutil
 
 import (
+	"database/sql"
 	"errors"
+	"os"
+	"strconv"
 	"testing"
 
 	"github.com/kidonchu/gitcli/testutil"
 )
 
-func TestConnect(t *testing.T) {
-	_, err := Connect("10.11.12.13", 3306, "kchu", "test")
+// connectTestMySQL connects to MySQL server named by GITCLI_TEST_MYSQL_HOST, along with
+// GITCLI_TEST_MYSQL_PORT, GITCLI_TEST_MYSQL_USER and GITCLI_TEST_MYSQL_PASS, skipping the test without it
+func connectTestMySQL(t *testing.T) *sql.DB {
+	host := os.Getenv("GITCLI_TEST_MYSQL_HOST")
+	if host == "" {
+		t.Skip("GITCLI_TEST_MYSQL_HOST is not set")
+	}
+
+	port := int64(3306)
+	if value := os.Getenv("GITCLI_TEST_MYSQL_PORT"); value != "" {
+		var err error
+		port, err = strconv.ParseInt(value, 10, 32)
+		testutil.CheckFatal(t, err)
+	}
+
+	dbh, err := Connect(host, int32(port), os.Getenv("GITCLI_TEST_MYSQL_USER"), os.Getenv("GITCLI_TEST_MYSQL_PASS"))
 	testutil.CheckFatal(t, err)
+	return dbh
+}
+
+func TestConnect(t *testing.T) {
+	// Connect pings the server before returning
+	connectTestMySQL(t).Close()
 }
 
 func TestDatabaseList(t *testing.T) {
@@ -35,14 +58,16 @@ func TestDatabaseList(t *testing.T) {
 }
 
 func TestDrop(t *testing.T) {
-	dbh, err := Connect("10.11.12.13", 3306, "kchu", "test")
-	_, err = dbh.Exec("CREATE DATABASE dbutil_test1")
+	dbh := connectTestMySQL(t)
+	defer dbh.Close()
+	_, err := dbh.Exec("CREATE DATABASE dbutil_test1")
 	testutil.CheckFatal(t, err)
 
 	_, err = dbh.Exec("USE dbutil_test1")
 	testutil.CheckFatal(t, err)
 
 	err = Drop(dbh, []string{"dbutil_test1"})
+	testutil.CheckFatal(t, err)
 
 	_, err = dbh.Exec("USE dbutil_test1")
 	if err == nil {
@@ -0,0 +1,167 @@
+package dbutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Driver manages story databases on a database server.
+// Operations give up once ctx is done, like when user hits Ctrl-C.
+type Driver interface {
+	// List returns databases whose name matches pattern
+	List(ctx context.Context, pattern string) ([]string, error)
+	// Create creates empty database
+	Create(ctx context.Context, name string) error
+	// Drop drops database
+	Drop(ctx context.Context, name string) error
+	// Clone creates target database as a copy of source database,
+	// leaving no target database it created behind when it fails
+	Clone(ctx context.Context, source string, target string) error
+	// Dump writes SQL dump of database to w
+	Dump(ctx context.Context, name string, w io.Writer) error
+	// Restore runs SQL dump read from r against database
+	Restore(ctx context.Context, name string, r io.Reader) error
+	// Command returns interactive client of database server connected to database,
+	// with credentials passed through env
+	Command(name string) (*exec.Cmd, error)
+	// Close releases connection to database server
+	Close() error
+}
+
+// Options tells which database server to use and how to access it
+type Options struct {
+	// Driver is one of `mysql`, `postgres` or `sqlite`, defaulting to mysql
+	Driver string
+	Host   string
+	Port   int32
+	User   string
+	Pass   string
+	// SSLMode is sslmode of postgres connections
+	SSLMode string
+	// Dir is where sqlite keeps a database file per story
+	Dir string
+	// Protected are glob patterns of databases never to be listed, dropped or overwritten,
+	// on top of system databases
+	Protected []string
+	// Timeout limits how long connecting to server may take, DefaultTimeout if zero
+	Timeout time.Duration
+	// Retries is how many more times connecting is tried when server does not answer
+	Retries int
+}
+
+// DefaultTimeout is how long connecting to database server may take by default
+const DefaultTimeout = 5 * time.Second
+
+// retryDelay is how long to wait before first retry, doubling every retry
+const retryDelay = 500 * time.Millisecond
+
+func withDefaults(opts Options) Options {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	return opts
+}
+
+// Open returns driver for database server in options, once the server answers
+func Open(ctx context.Context, opts Options) (Driver, error) {
+
+	var (
+		d   Driver
+		err error
+	)
+
+	switch strings.ToLower(opts.Driver) {
+	case "", "mysql":
+		if opts.Port == 0 {
+			opts.Port = 3306
+		}
+		d, err = openMySQL(ctx, opts)
+	case "postgres", "postgresql":
+		if opts.Port == 0 {
+			opts.Port = 5432
+		}
+		d, err = openPostgres(ctx, opts)
+	case "sqlite", "sqlite3":
+		d, err = openSQLite(opts)
+	default:
+		return nil, fmt.Errorf("Unknown database driver `%s`. Use one of mysql, postgres or sqlite", opts.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &guardedDriver{Driver: d, protected: opts.Protected}, nil
+}
+
+// matchNames returns names matching pattern
+func matchNames(names []string, pattern string) ([]string, error) {
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid database pattern `%s`: %+v", pattern, err)
+	}
+
+	var matched []string
+	for _, name := range names {
+		if regex.MatchString(name) {
+			matched = append(matched, name)
+		}
+	}
+
+	return matched, nil
+}
+
+// toolCommand prepares command line client of database server,
+// passing secrets through env so they do not show up in process list
+func toolCommand(ctx context.Context, env []string, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd
+}
+
+// runTool runs command line client of database server, killing it once ctx is done
+func runTool(ctx context.Context, env []string, stdin io.Reader, stdout io.Writer, name string, args ...string) error {
+
+	cmd := toolCommand(ctx, env, name, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("`%s` failed: %v\n%s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// pipe streams dump of source database into target database
+func pipe(ctx context.Context, d Driver, source string, target string) error {
+
+	r, w := io.Pipe()
+	dumped := make(chan error, 1)
+	go func() {
+		err := d.Dump(ctx, source, w)
+		w.CloseWithError(err)
+		dumped <- err
+	}()
+
+	err := d.Restore(ctx, target, r)
+	r.Close()
+	if dumpErr := <-dumped; dumpErr != nil {
+		return dumpErr
+	}
+
+	return err
+}
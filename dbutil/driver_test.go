@@ -0,0 +1,41 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestRunToolTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := runTool(ctx, nil, nil, nil, "sleep", "10")
+	if err != context.DeadlineExceeded {
+		testutil.CheckFatal(t, fmt.Errorf("Expected deadline to be exceeded, got %+v", err))
+	}
+	if time.Since(start) > 5*time.Second {
+		testutil.CheckFatal(t, fmt.Errorf("Expected tool to be killed at deadline"))
+	}
+}
+
+func TestRunToolCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := runTool(ctx, nil, nil, nil, "sleep", "10")
+	if err != context.Canceled {
+		testutil.CheckFatal(t, fmt.Errorf("Expected tool to be canceled, got %+v", err))
+	}
+}
+
+func TestMatchNamesInvalidPattern(t *testing.T) {
+	_, err := matchNames([]string{"story_1"}, "story_(")
+	if err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected invalid pattern to be reported"))
+	}
+}
@@ -0,0 +1,109 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Host is a named database server
+type Host struct {
+	Name   string
+	Driver Driver
+}
+
+// HostErrors collects failures of working with several hosts, by host name
+type HostErrors map[string]error
+
+func (errs HostErrors) Error() string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{fmt.Sprintf("Failed on %d database hosts:", len(errs))}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, strings.TrimSpace(errs[name].Error())))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ListAll lists databases matching pattern on every host concurrently, by host name.
+// Databases found on hosts that worked are returned along with errors of the others.
+func ListAll(ctx context.Context, hosts []Host, pattern string) (map[string][]string, error) {
+
+	var mu sync.Mutex
+	found := make(map[string][]string)
+	errs := eachHost(hosts, func(host Host) error {
+		dbs, err := host.Driver.List(ctx, pattern)
+		if err != nil {
+			return err
+		}
+		sort.Strings(dbs)
+
+		mu.Lock()
+		defer mu.Unlock()
+		found[host.Name] = dbs
+		return nil
+	})
+
+	return found, errs
+}
+
+// DropAll drops databases given by host name, hosts concurrently.
+// Databases on the same host are dropped one after another, stopping once ctx is done.
+func DropAll(ctx context.Context, hosts []Host, dbs map[string][]string) error {
+
+	var targets []Host
+	for _, host := range hosts {
+		if len(dbs[host.Name]) > 0 {
+			targets = append(targets, host)
+		}
+	}
+
+	return eachHost(targets, func(host Host) error {
+		for _, db := range dbs[host.Name] {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("Deleting database: `%s` on `%s`...\n", db, host.Name)
+			if err := host.Driver.Drop(ctx, db); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// eachHost runs fn against every host concurrently, collecting errors by host name
+func eachHost(hosts []Host, fn func(Host) error) error {
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = make(HostErrors)
+	)
+
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host Host) {
+			defer wg.Done()
+			err := fn(host)
+			if err != nil {
+				mu.Lock()
+				errs[host.Name] = err
+				mu.Unlock()
+			}
+		}(host)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
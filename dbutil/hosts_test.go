@@ -0,0 +1,55 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestListAndDropAll(t *testing.T) {
+	app, appDir := openTestSQLite(t)
+	defer os.RemoveAll(appDir)
+	analytics, analyticsDir := openTestSQLite(t)
+	defer os.RemoveAll(analyticsDir)
+
+	testutil.CheckFatal(t, app.Create(ctx, "story_app"))
+	testutil.CheckFatal(t, app.Create(ctx, "other"))
+	testutil.CheckFatal(t, analytics.Create(ctx, "story_events"))
+
+	hosts := []Host{{"app", app}, {"analytics", analytics}}
+
+	found, err := ListAll(ctx, hosts, "^story_")
+	testutil.CheckFatal(t, err)
+	if strings.Join(found["app"], ",") != "story_app" || strings.Join(found["analytics"], ",") != "story_events" {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected databases %v", found))
+	}
+
+	testutil.CheckFatal(t, DropAll(ctx, hosts, found))
+	found, err = ListAll(ctx, hosts, "")
+	testutil.CheckFatal(t, err)
+	if strings.Join(found["app"], ",") != "other" || len(found["analytics"]) != 0 {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected databases left %v", found))
+	}
+
+	// failure of one host does not hide databases of the others
+	found, err = ListAll(ctx, append(hosts, Host{"broken", unreachable{}}), "")
+	if _, ok := err.(HostErrors)["broken"]; !ok {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `broken` host to fail but got %v", err))
+	}
+	if strings.Join(found["app"], ",") != "other" {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected databases %v", found))
+	}
+}
+
+// unreachable is a host that cannot be connected to
+type unreachable struct {
+	Driver
+}
+
+func (unreachable) List(ctx context.Context, pattern string) ([]string, error) {
+	return nil, fmt.Errorf("connection refused")
+}
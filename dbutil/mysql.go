@@ -0,0 +1,91 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+)
+
+// mysqlDriver manages databases on MySQL server
+type mysqlDriver struct {
+	db   *sql.DB
+	opts Options
+}
+
+func openMySQL(ctx context.Context, opts Options) (Driver, error) {
+	db, err := connectMySQL(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlDriver{db: db, opts: opts}, nil
+}
+
+func (d *mysqlDriver) List(ctx context.Context, pattern string) ([]string, error) {
+	return FindDbsContext(ctx, d.db, pattern)
+}
+
+func (d *mysqlDriver) Create(ctx context.Context, name string) error {
+	if _, err := d.db.ExecContext(ctx, "CREATE DATABASE "+quoteMySQL(name)); err != nil {
+		return fmt.Errorf("Unable to create database `%s`: %+v", name, err)
+	}
+	return nil
+}
+
+func (d *mysqlDriver) Drop(ctx context.Context, name string) error {
+	if _, err := d.db.ExecContext(ctx, "DROP DATABASE "+quoteMySQL(name)); err != nil {
+		return fmt.Errorf("Error while deleting database `%s`: %+v", name, err)
+	}
+	return nil
+}
+
+// Clone copies source through mysqldump, as MySQL has no way of copying database
+func (d *mysqlDriver) Clone(ctx context.Context, source string, target string) error {
+	if err := d.Create(ctx, target); err != nil {
+		return err
+	}
+	if err := pipe(ctx, d, source, target); err != nil {
+		// do not leave half-filled database behind, even when interrupted
+		d.Drop(context.Background(), target)
+		return fmt.Errorf("Unable to clone database `%s` into `%s`\n%+v", source, target, err)
+	}
+	return nil
+}
+
+func (d *mysqlDriver) Dump(ctx context.Context, name string, w io.Writer) error {
+	return runTool(ctx, d.clientEnv(), nil, w, "mysqldump", d.dumpArgs(name)...)
+}
+
+func (d *mysqlDriver) Restore(ctx context.Context, name string, r io.Reader) error {
+	return runTool(ctx, d.clientEnv(), r, nil, "mysql", d.clientArgs(name)...)
+}
+
+func (d *mysqlDriver) Command(name string) (*exec.Cmd, error) {
+	return toolCommand(context.Background(), d.clientEnv(), "mysql", d.clientArgs(name)...), nil
+}
+
+func (d *mysqlDriver) Close() error {
+	return d.db.Close()
+}
+
+// clientArgs are arguments of mysql client connecting to database
+func (d *mysqlDriver) clientArgs(name string) []string {
+	timeout := strconv.Itoa(int(withDefaults(d.opts).Timeout.Seconds()))
+	return append(d.connectArgs(), "--connect-timeout", timeout, name)
+}
+
+// dumpArgs are arguments of mysqldump dumping database, which has no connect timeout option
+func (d *mysqlDriver) dumpArgs(name string) []string {
+	return append(d.connectArgs(), "--single-transaction", "--routines", "--triggers", name)
+}
+
+// connectArgs are connection arguments both clients take
+func (d *mysqlDriver) connectArgs() []string {
+	return []string{"-h", d.opts.Host, "-P", strconv.Itoa(int(d.opts.Port)), "-u", d.opts.User}
+}
+
+func (d *mysqlDriver) clientEnv() []string {
+	return []string{"MYSQL_PWD=" + d.opts.Pass}
+}
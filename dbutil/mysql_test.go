@@ -0,0 +1,30 @@
+package dbutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestMySQLToolArgs(t *testing.T) {
+	d := &mysqlDriver{opts: Options{Host: "db", Port: 3306, User: "foo", Timeout: 7 * time.Second}}
+
+	cases := map[string][2][]string{
+		"mysql":     {d.clientArgs("story_a"), {"-h", "db", "-P", "3306", "-u", "foo", "--connect-timeout", "7", "story_a"}},
+		"mysqldump": {d.dumpArgs("story_a"), {"-h", "db", "-P", "3306", "-u", "foo", "--single-transaction", "--routines", "--triggers", "story_a"}},
+	}
+	for tool, args := range cases {
+		if strings.Join(args[0], " ") != strings.Join(args[1], " ") {
+			testutil.CheckFatal(t, fmt.Errorf("Expected %s %v but got %v", tool, args[1], args[0]))
+		}
+	}
+
+	cmd, err := d.Command("story_a")
+	testutil.CheckFatal(t, err)
+	if strings.Join(cmd.Args[1:], " ") != strings.Join(cases["mysql"][1], " ") {
+		testutil.CheckFatal(t, fmt.Errorf("Expected mysql %v but got %v", cases["mysql"][1], cmd.Args[1:]))
+	}
+}
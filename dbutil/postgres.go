@@ -0,0 +1,130 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strconv"
+
+	_ "github.com/lib/pq" // postgres driver
+)
+
+// postgresDriver manages databases on PostgreSQL server
+type postgresDriver struct {
+	db   *sql.DB
+	opts Options
+}
+
+func openPostgres(ctx context.Context, opts Options) (Driver, error) {
+
+	opts = withDefaults(opts)
+	query := url.Values{"connect_timeout": {strconv.Itoa(int(opts.Timeout.Seconds()))}}
+	if opts.SSLMode != "" {
+		query.Set("sslmode", opts.SSLMode)
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(opts.User, opts.Pass),
+		Host:     net.JoinHostPort(opts.Host, strconv.Itoa(int(opts.Port))),
+		Path:     "/postgres",
+		RawQuery: query.Encode(),
+	}
+
+	db, err := sql.Open("postgres", dsn.String())
+	if err == nil {
+		err = ping(ctx, db, opts)
+		if err != nil {
+			db.Close()
+		}
+	}
+
+	if err != nil {
+		// do not print dsn, it contains password
+		return nil, fmt.Errorf("Unable to connect to database `%s@%s:%d`\n%+v", opts.User, opts.Host, opts.Port, err)
+	}
+
+	return &postgresDriver{db: db, opts: opts}, nil
+}
+
+func (d *postgresDriver) List(ctx context.Context, pattern string) ([]string, error) {
+
+	rows, err := d.db.QueryContext(ctx, "SELECT datname FROM pg_database WHERE NOT datistemplate")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get a list of databases: %+v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("Database could not be fetched from result rows: %+v", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to get a list of databases: %+v", err)
+	}
+
+	return matchNames(names, pattern)
+}
+
+func (d *postgresDriver) Create(ctx context.Context, name string) error {
+	if _, err := d.db.ExecContext(ctx, "CREATE DATABASE "+quotePostgres(name)); err != nil {
+		return fmt.Errorf("Unable to create database `%s`: %+v", name, err)
+	}
+	return nil
+}
+
+func (d *postgresDriver) Drop(ctx context.Context, name string) error {
+	if _, err := d.db.ExecContext(ctx, "DROP DATABASE "+quotePostgres(name)); err != nil {
+		return fmt.Errorf("Error while deleting database `%s`: %+v", name, err)
+	}
+	return nil
+}
+
+// Clone uses source as template, which requires nobody to be connected to it
+func (d *postgresDriver) Clone(ctx context.Context, source string, target string) error {
+	_, err := d.db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", quotePostgres(target), quotePostgres(source)))
+	if err != nil {
+		return fmt.Errorf("Unable to clone database `%s` into `%s`: %+v", source, target, err)
+	}
+	return nil
+}
+
+func (d *postgresDriver) Dump(ctx context.Context, name string, w io.Writer) error {
+	args := append(d.clientArgs(), "--no-owner", name)
+	return runTool(ctx, d.clientEnv(), nil, w, "pg_dump", args...)
+}
+
+func (d *postgresDriver) Restore(ctx context.Context, name string, r io.Reader) error {
+	args := append(d.clientArgs(), "-q", "-v", "ON_ERROR_STOP=1", "-d", name)
+	return runTool(ctx, d.clientEnv(), r, nil, "psql", args...)
+}
+
+func (d *postgresDriver) Command(name string) (*exec.Cmd, error) {
+	return toolCommand(context.Background(), d.clientEnv(), "psql", append(d.clientArgs(), "-d", name)...), nil
+}
+
+func (d *postgresDriver) Close() error {
+	return d.db.Close()
+}
+
+func (d *postgresDriver) clientArgs() []string {
+	return []string{"-h", d.opts.Host, "-p", strconv.Itoa(int(d.opts.Port)), "-U", d.opts.User}
+}
+
+func (d *postgresDriver) clientEnv() []string {
+	env := []string{
+		"PGPASSWORD=" + d.opts.Pass,
+		"PGCONNECT_TIMEOUT=" + strconv.Itoa(int(d.opts.Timeout.Seconds())),
+	}
+	if d.opts.SSLMode != "" {
+		env = append(env, "PGSSLMODE="+d.opts.SSLMode)
+	}
+	return env
+}
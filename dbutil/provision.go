@@ -0,0 +1,89 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxDbNameLength is the shortest limit among supported servers (MySQL's)
+const maxDbNameLength = 64
+
+var invalidDbNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Seed tells how new story database is filled
+type Seed struct {
+	// Database is cloned when set
+	Database string
+	// Files are SQL files run in order against empty database otherwise
+	Files []string
+}
+
+// StoryDbName builds name of story database from template like `{branch}_app`.
+// Characters not allowed in unquoted identifiers are replaced with underscores.
+func StoryDbName(template string, branch string) (string, error) {
+
+	if !strings.Contains(template, "{branch}") {
+		return "", fmt.Errorf("Database template `%s` must contain `{branch}`", template)
+	}
+
+	name := strings.Replace(template, "{branch}", strings.ToLower(branch), -1)
+	name = strings.Trim(invalidDbNameChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("Unable to build database name from `%s` for `%s`", template, branch)
+	}
+	if len(name) > maxDbNameLength {
+		return "", fmt.Errorf("Database name `%s` is longer than %d characters", name, maxDbNameLength)
+	}
+
+	return name, nil
+}
+
+// Provision creates database filled from seed, dropping it again if seeding fails.
+// Database it did not create, like the one that exists already, is never touched.
+func Provision(ctx context.Context, d Driver, name string, seed Seed) error {
+
+	existing, err := d.List(ctx, "^"+regexp.QuoteMeta(name)+"$")
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("Database `%s` already exists", name)
+	}
+
+	// Clone cleans up after itself
+	if seed.Database != "" {
+		return d.Clone(ctx, seed.Database, name)
+	}
+
+	if err := d.Create(ctx, name); err != nil {
+		return err
+	}
+
+	for _, path := range seed.Files {
+		if err := restoreFile(ctx, d, name, path); err != nil {
+			// drop what was created, even when interrupted
+			d.Drop(context.Background(), name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func restoreFile(ctx context.Context, d Driver, name string, path string) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read seed file `%s`: %+v", path, err)
+	}
+	defer file.Close()
+
+	if err := d.Restore(ctx, name, file); err != nil {
+		return fmt.Errorf("Unable to run seed file `%s` against `%s`\n%+v", path, name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package dbutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestStoryDbName(t *testing.T) {
+	cases := map[string]string{
+		"feature/ABC-12-login": "feature_abc_12_login_app",
+		"fix..x":               "fix_x_app",
+	}
+	for branch, expected := range cases {
+		name, err := StoryDbName("{branch}_app", branch)
+		testutil.CheckFatal(t, err)
+		if name != expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", expected, name))
+		}
+	}
+
+	if _, err := StoryDbName("app", "feature"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected template without `{branch}` to be rejected"))
+	}
+	if _, err := StoryDbName("{branch}", strings.Repeat("a", 65)); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected too long name to be rejected"))
+	}
+}
+
+func TestProvision(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+
+	d, dir := openTestSQLite(t)
+	defer os.RemoveAll(dir)
+
+	schema := filepath.Join(dir, "schema.sql")
+	seed := filepath.Join(dir, "seed.sql")
+	testutil.CheckFatal(t, ioutil.WriteFile(schema, []byte("CREATE TABLE users (name TEXT);"), 0644))
+	testutil.CheckFatal(t, ioutil.WriteFile(seed, []byte("INSERT INTO users VALUES ('kchu');"), 0644))
+
+	testutil.CheckFatal(t, Provision(ctx, d, "seeded", Seed{Files: []string{schema, seed}}))
+	testutil.CheckFatal(t, Provision(ctx, d, "cloned", Seed{Database: "seeded"}))
+
+	var dump bytes.Buffer
+	testutil.CheckFatal(t, d.Dump(ctx, "cloned", &dump))
+	if !strings.Contains(dump.String(), "'kchu'") {
+		testutil.CheckFatal(t, fmt.Errorf("Expected cloned database to have seeded rows\n%s", dump.String()))
+	}
+
+	// failed seeding leaves nothing behind
+	broken := filepath.Join(dir, "broken.sql")
+	testutil.CheckFatal(t, ioutil.WriteFile(broken, []byte("INSERT INTO missing VALUES (1);"), 0644))
+	if err := Provision(ctx, d, "broken", Seed{Files: []string{broken}}); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected broken seed file to fail"))
+	}
+	dbs, err := d.List(ctx, "^broken$")
+	testutil.CheckFatal(t, err)
+	if len(dbs) != 0 {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `broken` to be dropped"))
+	}
+}
+
+// racingDriver is a server where someone else creates the database right after it is listed
+type racingDriver struct {
+	Driver
+	dbs map[string]bool
+}
+
+func (d *racingDriver) List(ctx context.Context, pattern string) ([]string, error) {
+	var names []string
+	for name := range d.dbs {
+		names = append(names, name)
+	}
+	return matchNames(names, pattern)
+}
+
+func (d *racingDriver) Create(ctx context.Context, name string) error {
+	d.dbs[name] = true
+	return fmt.Errorf("database exists")
+}
+
+func (d *racingDriver) Clone(ctx context.Context, source string, target string) error {
+	return d.Create(ctx, target)
+}
+
+func (d *racingDriver) Drop(ctx context.Context, name string) error {
+	delete(d.dbs, name)
+	return nil
+}
+
+func TestProvisionNotCreated(t *testing.T) {
+	d := &racingDriver{dbs: map[string]bool{"seed": true, "taken": true}}
+
+	// database that was there before is left alone
+	if err := Provision(ctx, d, "taken", Seed{Database: "seed"}); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected existing database to be refused"))
+	}
+
+	// so is the one created by someone else meanwhile
+	if err := Provision(ctx, d, "story_app", Seed{Files: []string{"schema.sql"}}); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected failing create to fail"))
+	}
+	if err := Provision(ctx, d, "story_log", Seed{Database: "seed"}); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected failing clone to fail"))
+	}
+
+	for _, name := range []string{"taken", "story_app", "story_log"} {
+		if !d.dbs[name] {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` not created by provision to be kept", name))
+		}
+	}
+}
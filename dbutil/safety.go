@@ -0,0 +1,136 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// builtinProtected are system databases of supported servers, which are never
+// listed or dropped as story databases
+var builtinProtected = []string{
+	"mysql", "information_schema", "performance_schema", "sys",
+	"postgres", "template0", "template1",
+}
+
+// IsProtected tells whether database is a system database or matches
+// one of given glob patterns, like `prod_*`
+func IsProtected(name string, protected []string) bool {
+
+	name = strings.ToLower(name)
+	for _, pattern := range append(builtinProtected, protected...) {
+		if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateName rejects names that cannot be quoted safely, point outside of sqlite
+// directory, or could be taken for an option by command line clients
+func ValidateName(name string) error {
+
+	switch {
+	case name == "":
+		return fmt.Errorf("Database name is empty")
+	case strings.ContainsAny(name, "\x00/\\"):
+		return fmt.Errorf("Database name `%s` contains invalid characters", name)
+	case strings.HasPrefix(name, "-") || strings.HasPrefix(name, "."):
+		return fmt.Errorf("Database name `%s` cannot start with `%c`", name, name[0])
+	}
+
+	return nil
+}
+
+// quoteMySQL quotes identifier for MySQL, escaping backticks in it
+func quoteMySQL(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// quotePostgres quotes identifier for PostgreSQL, escaping double quotes in it
+func quotePostgres(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// guardedDriver keeps driver away from protected databases and invalid names
+type guardedDriver struct {
+	Driver
+	protected []string
+}
+
+func (d *guardedDriver) List(ctx context.Context, pattern string) ([]string, error) {
+
+	names, err := d.Driver.List(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []string
+	for _, name := range names {
+		if !IsProtected(name, d.protected) {
+			allowed = append(allowed, name)
+		}
+	}
+
+	return allowed, nil
+}
+
+func (d *guardedDriver) Create(ctx context.Context, name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	return d.Driver.Create(ctx, name)
+}
+
+func (d *guardedDriver) Drop(ctx context.Context, name string) error {
+	if err := d.checkWritable(name); err != nil {
+		return err
+	}
+	return d.Driver.Drop(ctx, name)
+}
+
+func (d *guardedDriver) Clone(ctx context.Context, source string, target string) error {
+	if err := ValidateName(source); err != nil {
+		return err
+	}
+	if err := d.checkWritable(target); err != nil {
+		return err
+	}
+	return d.Driver.Clone(ctx, source, target)
+}
+
+func (d *guardedDriver) Dump(ctx context.Context, name string, w io.Writer) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	return d.Driver.Dump(ctx, name, w)
+}
+
+func (d *guardedDriver) Restore(ctx context.Context, name string, r io.Reader) error {
+	if err := d.checkWritable(name); err != nil {
+		return err
+	}
+	return d.Driver.Restore(ctx, name, r)
+}
+
+func (d *guardedDriver) Command(name string) (*exec.Cmd, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	return d.Driver.Command(name)
+}
+
+// checkWritable makes sure database can be dropped or overwritten
+func (d *guardedDriver) checkWritable(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if IsProtected(name, d.protected) {
+		return fmt.Errorf("Database `%s` is protected", name)
+	}
+	return nil
+}
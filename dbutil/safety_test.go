@@ -0,0 +1,80 @@
+package dbutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestIsProtected(t *testing.T) {
+	protected := []string{"prod_*", "billing"}
+
+	for _, name := range []string{"mysql", "INFORMATION_SCHEMA", "template1", "prod_app", "Billing"} {
+		if !IsProtected(name, protected) {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be protected", name))
+		}
+	}
+	for _, name := range []string{"story_app", "production", "billing_story"} {
+		if IsProtected(name, protected) {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` not to be protected", name))
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	testutil.CheckFatal(t, ValidateName("story_app"))
+
+	for _, name := range []string{"", "../etc", "a/b", "a\\b", "-e", ".hidden", "a\x00b"} {
+		if err := ValidateName(name); err == nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%q` to be rejected", name))
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if quoted := quoteMySQL("a`; DROP DATABASE b; `"); quoted != "`a``; DROP DATABASE b; ```" {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected MySQL identifier %s", quoted))
+	}
+	if quoted := quotePostgres(`a"b`); quoted != `"a""b"` {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected PostgreSQL identifier %s", quoted))
+	}
+}
+
+func TestGuardedDriver(t *testing.T) {
+	dir, d := openProtectedSQLite(t, []string{"prod_*"})
+	defer os.RemoveAll(dir)
+
+	testutil.CheckFatal(t, d.Create(ctx, "prod_app"))
+	testutil.CheckFatal(t, d.Create(ctx, "story_app"))
+
+	// empty pattern matches everything but protected databases
+	dbs, err := d.List(ctx, "")
+	testutil.CheckFatal(t, err)
+	if strings.Join(dbs, ",") != "story_app" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected only `story_app` but got %v", dbs))
+	}
+
+	if err := d.Drop(ctx, "prod_app"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected protected database not to be dropped"))
+	}
+	if err := d.Clone(ctx, "story_app", "prod_app"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected protected database not to be overwritten"))
+	}
+	if err := d.Drop(ctx, "../story_app"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected invalid name to be rejected"))
+	}
+	testutil.CheckFatal(t, d.Drop(ctx, "story_app"))
+}
+
+func openProtectedSQLite(t *testing.T, protected []string) (string, Driver) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	testutil.CheckFatal(t, err)
+
+	d, err := Open(ctx, Options{Driver: "sqlite", Dir: dir, Protected: protected})
+	testutil.CheckFatal(t, err)
+	return dir, d
+}
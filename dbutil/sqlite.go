@@ -0,0 +1,135 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// sqliteExt is extension of sqlite database files
+const sqliteExt = ".sqlite3"
+
+// sqliteDriver keeps a sqlite database file per story in a directory
+type sqliteDriver struct {
+	dir string
+}
+
+func openSQLite(opts Options) (Driver, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("Directory of sqlite databases is not specified")
+	}
+	return &sqliteDriver{dir: opts.Dir}, nil
+}
+
+func (d *sqliteDriver) path(name string) string {
+	return filepath.Join(d.dir, name+sqliteExt)
+}
+
+func (d *sqliteDriver) List(ctx context.Context, pattern string) ([]string, error) {
+
+	files, err := ioutil.ReadDir(d.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get a list of databases: %+v", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), sqliteExt) {
+			names = append(names, strings.TrimSuffix(file.Name(), sqliteExt))
+		}
+	}
+
+	return matchNames(names, pattern)
+}
+
+// Create creates empty file, which sqlite treats as empty database
+func (d *sqliteDriver) Create(ctx context.Context, name string) error {
+
+	file, err := d.createFile(name)
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func (d *sqliteDriver) Drop(ctx context.Context, name string) error {
+
+	if err := os.Remove(d.path(name)); err != nil {
+		return fmt.Errorf("Error while deleting database `%s`: %+v", name, err)
+	}
+
+	// leftovers of interrupted transactions
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		os.Remove(d.path(name) + suffix)
+	}
+
+	return nil
+}
+
+func (d *sqliteDriver) Clone(ctx context.Context, source string, target string) error {
+
+	src, err := os.Open(d.path(source))
+	if err != nil {
+		return fmt.Errorf("Unable to clone database `%s`: %+v", source, err)
+	}
+	defer src.Close()
+
+	dst, err := d.createFile(target)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(d.path(target))
+		return fmt.Errorf("Unable to clone database `%s` into `%s`: %+v", source, target, err)
+	}
+
+	return dst.Close()
+}
+
+func (d *sqliteDriver) Dump(ctx context.Context, name string, w io.Writer) error {
+	if _, err := os.Stat(d.path(name)); err != nil {
+		return fmt.Errorf("Unable to dump database `%s`: %+v", name, err)
+	}
+	return runTool(ctx, nil, nil, w, "sqlite3", d.path(name), ".dump")
+}
+
+func (d *sqliteDriver) Restore(ctx context.Context, name string, r io.Reader) error {
+	return runTool(ctx, nil, r, nil, "sqlite3", "-bail", d.path(name))
+}
+
+func (d *sqliteDriver) Command(name string) (*exec.Cmd, error) {
+	if _, err := os.Stat(d.path(name)); err != nil {
+		return nil, fmt.Errorf("Unable to connect to database `%s`: %+v", name, err)
+	}
+	return toolCommand(context.Background(), nil, "sqlite3", d.path(name)), nil
+}
+
+func (d *sqliteDriver) Close() error {
+	return nil
+}
+
+// createFile creates database file, failing if database already exists
+func (d *sqliteDriver) createFile(name string) (*os.File, error) {
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return nil, fmt.Errorf("Unable to create database `%s`: %+v", name, err)
+	}
+
+	file, err := os.OpenFile(d.path(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create database `%s`: %+v", name, err)
+	}
+
+	return file, nil
+}
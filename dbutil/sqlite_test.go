@@ -0,0 +1,92 @@
+package dbutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+// ctx is used by tests that are not about cancellation
+var ctx = context.Background()
+
+func openTestSQLite(t *testing.T) (Driver, string) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	testutil.CheckFatal(t, err)
+
+	d, err := Open(ctx, Options{Driver: "sqlite", Dir: dir})
+	testutil.CheckFatal(t, err)
+	return d, dir
+}
+
+func TestSQLite(t *testing.T) {
+	d, dir := openTestSQLite(t)
+	defer os.RemoveAll(dir)
+	defer d.Close()
+
+	testutil.CheckFatal(t, d.Create(ctx, "story_a"))
+	testutil.CheckFatal(t, d.Create(ctx, "other"))
+	if err := d.Create(ctx, "story_a"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected existing database not to be created again"))
+	}
+
+	testutil.CheckFatal(t, d.Clone(ctx, "story_a", "story_b"))
+
+	dbs, err := d.List(ctx, "^story_")
+	testutil.CheckFatal(t, err)
+	if strings.Join(dbs, ",") != "story_a,story_b" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `story_a,story_b` but got `%s`", strings.Join(dbs, ",")))
+	}
+
+	testutil.CheckFatal(t, d.Drop(ctx, "story_a"))
+	dbs, err = d.List(ctx, "^story_")
+	testutil.CheckFatal(t, err)
+	if strings.Join(dbs, ",") != "story_b" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `story_b` but got `%s`", strings.Join(dbs, ",")))
+	}
+
+	if err := d.Drop(ctx, "story_a"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected dropping missing database to fail"))
+	}
+}
+
+func TestSQLiteDumpRestore(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+
+	d, dir := openTestSQLite(t)
+	defer os.RemoveAll(dir)
+	defer d.Close()
+
+	seed := "CREATE TABLE users (name TEXT); INSERT INTO users VALUES ('kchu');"
+	testutil.CheckFatal(t, d.Create(ctx, "seed"))
+	testutil.CheckFatal(t, d.Restore(ctx, "seed", strings.NewReader(seed)))
+
+	var dump bytes.Buffer
+	testutil.CheckFatal(t, d.Dump(ctx, "seed", &dump))
+
+	testutil.CheckFatal(t, d.Create(ctx, "story"))
+	testutil.CheckFatal(t, d.Restore(ctx, "story", &dump))
+
+	var restored bytes.Buffer
+	testutil.CheckFatal(t, d.Dump(ctx, "story", &restored))
+	if !strings.Contains(restored.String(), "'kchu'") {
+		testutil.CheckFatal(t, fmt.Errorf("Expected restored database to have seeded rows\n%s", restored.String()))
+	}
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	if _, err := Open(ctx, Options{Driver: "oracle"}); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected unknown driver to be rejected"))
+	}
+	if _, err := Open(ctx, Options{Driver: "sqlite"}); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected sqlite without directory to be rejected"))
+	}
+}
@@ -0,0 +1,236 @@
+package gitutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// defaultBranchTemplate is used to build branch name from title when none is configured.
+// Placeholders without value are dropped along with their separators.
+const defaultBranchTemplate = "{type}/{issue}-{slug}"
+
+var placeholderPattern = regexp.MustCompile(`\{[a-z]+\}`)
+
+// BranchNameRules are naming conventions story branches must follow
+type BranchNameRules struct {
+	// Template builds branch name from `{type}`, `{issue}` and `{slug}`
+	Template string
+	// Prefixes lists allowed prefixes, any prefix is allowed when empty
+	Prefixes []string
+	// MaxLength limits length of branch name, unlimited when zero
+	MaxLength int
+	// Pattern is regex branch name must match, if set
+	Pattern *regexp.Regexp
+}
+
+// BranchNameVars are values filling placeholders of branch template
+type BranchNameVars struct {
+	Type  string
+	Issue string
+	Title string
+}
+
+// LoadBranchNameRules reads naming conventions from `story.branch.*` config
+func LoadBranchNameRules() (*BranchNameRules, error) {
+
+	rules := &BranchNameRules{Template: defaultBranchTemplate}
+
+	if template, _ := ConfigString("story.branch.template"); template != "" {
+		rules.Template = template
+	}
+
+	if prefixes, _ := ConfigString("story.branch.prefixes"); prefixes != "" {
+		for _, prefix := range strings.Split(prefixes, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				rules.Prefixes = append(rules.Prefixes, prefix)
+			}
+		}
+	}
+
+	if maxLength, err := ConfigInt32("story.branch.maxlength"); err == nil {
+		rules.MaxLength = int(maxLength)
+	}
+
+	if pattern, _ := ConfigString("story.branch.pattern"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regex in `story.branch.pattern`\n%+v", err)
+		}
+		rules.Pattern = re
+	}
+
+	return rules, nil
+}
+
+// Build creates branch name from template, shortening slug to fit max length
+func (r *BranchNameRules) Build(vars BranchNameVars) (string, error) {
+
+	slug := Slugify(vars.Title)
+	if slug == "" {
+		return "", fmt.Errorf("Unable to create branch name from title `%s`", vars.Title)
+	}
+
+	name, err := renderBranchTemplate(r.Template, vars, slug)
+	if err != nil {
+		return "", err
+	}
+
+	if r.MaxLength > 0 && len(name) > r.MaxLength && strings.Contains(r.Template, "{slug}") {
+		overflow := len(name) - r.MaxLength
+		if overflow < len(slug) {
+			slug = shortenSlug(slug, len(slug)-overflow)
+			name, err = renderBranchTemplate(r.Template, vars, slug)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return name, r.Validate(name)
+}
+
+// Validate checks branch name against git ref rules and naming conventions
+func (r *BranchNameRules) Validate(name string) error {
+
+	if err := CheckRefFormat(name); err != nil {
+		return err
+	}
+
+	if len(r.Prefixes) > 0 {
+		allowed := false
+		for _, prefix := range r.Prefixes {
+			if strings.HasPrefix(name, prefix) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("Branch name `%s` must start with one of `%s`", name, strings.Join(r.Prefixes, "`, `"))
+		}
+	}
+
+	if r.MaxLength > 0 && len(name) > r.MaxLength {
+		return fmt.Errorf("Branch name `%s` is longer than %d characters", name, r.MaxLength)
+	}
+
+	if r.Pattern != nil && !r.Pattern.MatchString(name) {
+		return fmt.Errorf("Branch name `%s` does not match `%s`", name, r.Pattern.String())
+	}
+
+	return nil
+}
+
+// renderBranchTemplate fills placeholders, dropping path components and
+// separators left over from placeholders without value
+func renderBranchTemplate(template string, vars BranchNameVars, slug string) (string, error) {
+
+	values := map[string]string{
+		"{type}":  Slugify(vars.Type),
+		"{issue}": strings.TrimSpace(vars.Issue),
+		"{slug}":  slug,
+	}
+
+	var unknown string
+	rendered := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := values[placeholder]
+		if !ok {
+			unknown = placeholder
+		}
+		return value
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("Unknown placeholder `%s` in branch template `%s`", unknown, template)
+	}
+
+	var components []string
+	for _, component := range strings.Split(rendered, "/") {
+		component = strings.Trim(component, "-_.")
+		if component != "" {
+			components = append(components, component)
+		}
+	}
+
+	return strings.Join(components, "/"), nil
+}
+
+// shortenSlug cuts slug to given length, dropping the word cut in the middle
+func shortenSlug(slug string, length int) string {
+	short := slug[:length]
+	if slug[length] != '-' {
+		if i := strings.LastIndex(short, "-"); i > 0 {
+			short = short[:i]
+		}
+	}
+	return strings.TrimRight(short, "-")
+}
+
+// Slugify turns free text into lowercase words joined by dashes
+func Slugify(text string) string {
+
+	var words []string
+	var word []rune
+	for _, r := range strings.ToLower(text) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			word = append(word, r)
+			continue
+		}
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	return strings.Join(words, "-")
+}
+
+// CheckRefFormat validates branch name the way `git check-ref-format --branch` does
+func CheckRefFormat(name string) error {
+
+	invalid := func(reason string) error {
+		return fmt.Errorf("`%s` is not a valid branch name: %s", name, reason)
+	}
+
+	switch {
+	case name == "":
+		return fmt.Errorf("Branch name is empty")
+	case name == "@" || name == "HEAD":
+		return invalid("reserved name")
+	case strings.HasPrefix(name, "-"):
+		return invalid("cannot start with `-`")
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/"):
+		return invalid("cannot start or end with `/`")
+	case strings.HasSuffix(name, "."):
+		return invalid("cannot end with `.`")
+	case strings.Contains(name, ".."):
+		return invalid("cannot contain `..`")
+	case strings.Contains(name, "//"):
+		return invalid("cannot contain `//`")
+	case strings.Contains(name, "@{"):
+		return invalid("cannot contain `@{`")
+	}
+
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return invalid("cannot contain control characters")
+		}
+		if strings.ContainsRune(" ~^:?*[\\", r) {
+			return invalid(fmt.Sprintf("cannot contain `%c`", r))
+		}
+	}
+
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") {
+			return invalid("path components cannot start with `.`")
+		}
+		if strings.HasSuffix(component, ".lock") {
+			return invalid("path components cannot end with `.lock`")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package gitutil
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Fix login page":               "fix-login-page",
+		"  Add [WIP] support: v2.0!  ": "add-wip-support-v2-0",
+		"Café menu":                    "caf-menu",
+		"???":                          "",
+	}
+	for title, expected := range cases {
+		if result := Slugify(title); result != expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", expected, result))
+		}
+	}
+}
+
+func TestCheckRefFormat(t *testing.T) {
+	valid := []string{"feature/ABC-12-login", "fix_1", "a/b/c"}
+	for _, name := range valid {
+		if err := CheckRefFormat(name); err != nil {
+			testutil.CheckFatal(t, err)
+		}
+	}
+
+	invalid := []string{"", "@", "HEAD", "-x", "/x", "x/", "x.", "a..b", "a//b", "a@{1}",
+		"a b", "a~1", "a^", "a:b", "a?", "a*", "a[b", "a\\b", "a/.b", "a/b.lock", "a\tb"}
+	for _, name := range invalid {
+		if err := CheckRefFormat(name); err == nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be invalid", name))
+		}
+	}
+}
+
+func TestBuildBranchName(t *testing.T) {
+	rules := &BranchNameRules{Template: defaultBranchTemplate}
+
+	cases := []struct {
+		vars     BranchNameVars
+		expected string
+	}{
+		{BranchNameVars{Type: "Feature", Issue: "ABC-12", Title: "Login page"}, "feature/ABC-12-login-page"},
+		{BranchNameVars{Type: "bugfix", Title: "Login page"}, "bugfix/login-page"},
+		{BranchNameVars{Title: "Login page"}, "login-page"},
+	}
+	for _, c := range cases {
+		result, err := rules.Build(c.vars)
+		testutil.CheckFatal(t, err)
+		if result != c.expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", c.expected, result))
+		}
+	}
+
+	// slug is shortened at word boundary to fit max length
+	rules.MaxLength = 22
+	result, err := rules.Build(BranchNameVars{Type: "feature", Title: "a very long title for a branch"})
+	testutil.CheckFatal(t, err)
+	if result != "feature/a-very-long" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `feature/a-very-long` but got `%s`", result))
+	}
+
+	if _, err := (&BranchNameRules{Template: "{kind}/{slug}"}).Build(BranchNameVars{Title: "x"}); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected unknown placeholder to fail"))
+	}
+}
+
+func TestValidateBranchName(t *testing.T) {
+	rules := &BranchNameRules{
+		Prefixes:  []string{"feature/", "bugfix/"},
+		MaxLength: 30,
+		Pattern:   regexp.MustCompile(`^[a-z]+/[A-Z]+-[0-9]+`),
+	}
+
+	testutil.CheckFatal(t, rules.Validate("feature/ABC-12-login"))
+
+	invalid := []string{"hotfix/ABC-12", "feature/ABC-12-a-very-long-branch-name", "feature/login", "feature/ABC-12 x"}
+	for _, name := range invalid {
+		if err := rules.Validate(name); err == nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be rejected", name))
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package gitutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ConfigType is kind of value a config key takes
+type ConfigType int
+
+// Kinds of config values
+const (
+	ConfigTypeString ConfigType = iota
+	ConfigTypeInt
+	ConfigTypeBool
+	ConfigTypeRegex
+	ConfigTypePath
+	ConfigTypeList
+	ConfigTypeEnum
+)
+
+func (t ConfigType) String() string {
+	switch t {
+	case ConfigTypeInt:
+		return "int"
+	case ConfigTypeBool:
+		return "bool"
+	case ConfigTypeRegex:
+		return "regex"
+	case ConfigTypePath:
+		return "path"
+	case ConfigTypeList:
+		return "list"
+	case ConfigTypeEnum:
+		return "enum"
+	}
+	return "string"
+}
+
+// ConfigKey describes a `story.*` config key.
+// `*` in Name stands for any name, like the source name in `story.source.*`.
+type ConfigKey struct {
+	Name    string
+	Type    ConfigType
+	Values  []string // allowed values of ConfigTypeEnum
+	Secret  bool     // read with ConfigSecret, so `<name>cmd` and `<name>file` are valid too
+	Command bool     // value is a command gitcli runs
+	// Personal keys weaken security, tell where secrets are sent or write outside of repository,
+	// so shared config cannot set them
+	Personal bool
+	Usage    string
+}
+
+// dbHostKeys are keys every database host takes, under `story.hosteddb` or `story.db.<name>`
+var dbHostKeys = []ConfigKey{
+	{Name: "driver", Type: ConfigTypeEnum, Values: []string{"mysql", "postgres", "postgresql", "sqlite", "sqlite3"}, Usage: "Database server kind, mysql by default"},
+	{Name: "host", Personal: true, Usage: "Address of database server, which gets the password"},
+	{Name: "port", Type: ConfigTypeInt, Usage: "Port of database server"},
+	{Name: "user", Usage: "User to connect to database server as"},
+	{Name: "pass", Secret: true, Usage: "Password of database user"},
+	{Name: "sslmode", Usage: "SSL mode of postgres connections"},
+	{Name: "dir", Type: ConfigTypePath, Usage: "Directory sqlite databases are kept in"},
+	{Name: "template", Type: ConfigTypeList, Usage: "Names of story databases, with {branch} replaced by branch name"},
+	{Name: "seed", Type: ConfigTypeList, Usage: "Databases story databases are cloned from"},
+	{Name: "seedfiles", Type: ConfigTypeList, Usage: "SQL files run against new story databases"},
+	{Name: "protected", Type: ConfigTypeList, Usage: "Glob patterns of databases never dropped on this host"},
+	{Name: "timeout", Type: ConfigTypeInt, Usage: "Seconds to wait for this host to answer"},
+}
+
+// ConfigSchema lists every config key gitcli reads
+var ConfigSchema = buildConfigSchema()
+
+func buildConfigSchema() []ConfigKey {
+
+	keys := []ConfigKey{
+		{Name: "story.source.*", Usage: "Source branch of stories as `<remote>/<branch>`, `default` when --source is not given"},
+		{Name: "story.remote.target", Usage: "Remote stories are pushed to and pull requests are opened against"},
+		{Name: "story.mostrecent", Usage: "Branch switched away from most recently, kept by gitcli"},
+		{Name: "story.sync.top", Usage: "Story the stopped story sync was started from, kept by gitcli"},
+		{Name: "story.editor", Command: true, Usage: "Editor for pull request messages"},
+		{Name: "story.insecure", Personal: true, Type: ConfigTypeBool, Usage: "If true, do not verify certificates and host keys of remotes"},
+		{Name: "story.ssh.privatekey", Personal: true, Type: ConfigTypePath, Usage: "Private key to authenticate to remotes with"},
+		{Name: "story.ssh.publickey", Personal: true, Type: ConfigTypePath, Usage: "Public key of story.ssh.privatekey"},
+		{Name: "story.ssh.knownhosts", Personal: true, Type: ConfigTypePath, Usage: "known_hosts file to verify host keys of remotes with"},
+		{Name: "story.oauthtoken", Secret: true, Usage: "GitHub token to open pull requests with"},
+		{Name: "story.issuePrefix", Usage: "Prefix of issue numbers in pull request titles"},
+		{Name: "story.issueBranchPattern", Type: ConfigTypeRegex, Usage: "Pattern finding issue number in branch name"},
+		{Name: "story.issue.provider", Type: ConfigTypeEnum, Values: []string{"github", "gitlab", "jira"}, Usage: "Issue tracker"},
+		{Name: "story.issue.url", Personal: true, Usage: "Address of issue tracker, which gets the token"},
+		{Name: "story.issue.repo", Usage: "Repository or project of issues"},
+		{Name: "story.issue.user", Usage: "User to authenticate to issue tracker as"},
+		{Name: "story.issue.token", Secret: true, Usage: "Token of issue tracker"},
+		{Name: "story.issue.progress", Usage: "State issues are moved to when story starts"},
+		{Name: "story.issue.start", Type: ConfigTypeBool, Usage: "If true, move issue to story.issue.progress on story new"},
+		{Name: "story.branch.template", Usage: "Template of branch names, e.g. {type}/{issue}-{slug}"},
+		{Name: "story.branch.prefixes", Type: ConfigTypeList, Usage: "Allowed branch name prefixes"},
+		{Name: "story.branch.maxlength", Type: ConfigTypeInt, Usage: "Maximum length of branch names"},
+		{Name: "story.branch.pattern", Type: ConfigTypeRegex, Usage: "Pattern branch names must match"},
+		{Name: "story.fetch.prune", Type: ConfigTypeBool, Usage: "If true, prune remote branches on fetch"},
+		{Name: "story.fetch.jobs", Type: ConfigTypeInt, Usage: "Remotes fetched at the same time"},
+		{Name: "story.fetch.timeout", Type: ConfigTypeInt, Usage: "Seconds a fetch may take"},
+		{Name: "story.fetch.tags", Type: ConfigTypeEnum, Values: []string{"auto", "none", "all"}, Usage: "Tags downloaded on fetch"},
+		{Name: "story.fetch.singlebranch", Type: ConfigTypeBool, Usage: "If true, fetch only the source branch"},
+		{Name: "story.pull.autostash", Type: ConfigTypeBool, Usage: "If true, stash changes before pulling"},
+		{Name: "story.pull.sources", Type: ConfigTypeList, Usage: "Extra sources pulled with --all-sources"},
+		{Name: "story.envtemplate", Usage: "Template of story env file"},
+		{Name: "story.envfile", Personal: true, Type: ConfigTypePath, Usage: "Story env file, .env.story by default"},
+		{Name: "story.db.protected", Type: ConfigTypeList, Usage: "Glob patterns of databases never dropped on any host"},
+		{Name: "story.db.timeout", Type: ConfigTypeInt, Usage: "Seconds to wait for database hosts to answer"},
+		{Name: "story.db.retries", Type: ConfigTypeInt, Usage: "Times connecting to database hosts is retried"},
+		{Name: "story.db.confirmthreshold", Type: ConfigTypeInt, Usage: "Databases dropped at once without typing their names"},
+	}
+
+	for _, prefix := range []string{"story.hosteddb.", "story.db.*."} {
+		for _, key := range dbHostKeys {
+			key.Name = prefix + key.Name
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// LookupConfigKey finds schema of given config name.
+// Like git, section and key names are case-insensitive.
+func LookupConfigKey(name string) (ConfigKey, error) {
+
+	for _, key := range ConfigSchema {
+		if key.matches(name) {
+			return key, nil
+		}
+	}
+
+	// `<name>cmd` and `<name>file` of secrets
+	for _, suffix := range []string{"cmd", "file"} {
+		if !strings.HasSuffix(strings.ToLower(name), suffix) {
+			continue
+		}
+		key, err := LookupConfigKey(name[:len(name)-len(suffix)])
+		if err == nil && key.Secret {
+			key.Name += suffix
+			key.Type = ConfigTypeString
+			key.Command = true
+			if suffix == "file" {
+				key.Type = ConfigTypePath
+				key.Command = false
+			}
+			key.Secret = false
+			return key, nil
+		}
+	}
+
+	return ConfigKey{}, fmt.Errorf("Unknown config `%s`", name)
+}
+
+// matches tells whether config name is described by key
+func (k ConfigKey) matches(name string) bool {
+
+	patternParts := strings.Split(k.Name, ".")
+	nameParts := strings.Split(name, ".")
+	if len(patternParts) != len(nameParts) {
+		return false
+	}
+
+	for i, part := range patternParts {
+		if part == "*" {
+			if nameParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if !strings.EqualFold(part, nameParts[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate checks that value can be used for key
+func (k ConfigKey) Validate(value string) error {
+
+	switch k.Type {
+	case ConfigTypeInt:
+		if _, err := strconv.ParseInt(value, 10, 32); err != nil {
+			return fmt.Errorf("`%s` must be a number, not `%s`", k.Name, value)
+		}
+	case ConfigTypeBool:
+		if _, err := parseConfigBool(value); err != nil {
+			return fmt.Errorf("`%s` must be true or false, not `%s`", k.Name, value)
+		}
+	case ConfigTypeRegex:
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("`%s` is not a valid regex\n%+v", k.Name, err)
+		}
+	case ConfigTypeEnum:
+		for _, allowed := range k.Values {
+			if strings.EqualFold(value, allowed) {
+				return nil
+			}
+		}
+		return fmt.Errorf("`%s` must be one of %s, not `%s`", k.Name, strings.Join(k.Values, ", "), value)
+	}
+
+	return nil
+}
+
+// parseConfigBool reads bool the way git does
+func parseConfigBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("Invalid bool `%s`", value)
+}
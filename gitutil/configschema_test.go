@@ -0,0 +1,69 @@
+package gitutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestLookupConfigKey(t *testing.T) {
+	cases := map[string]string{
+		"story.source.default":     "story.source.*",
+		"story.issueprefix":        "story.issuePrefix",
+		"story.hosteddb.port":      "story.hosteddb.port",
+		"story.db.app.timeout":     "story.db.*.timeout",
+		"story.db.timeout":         "story.db.timeout",
+		"story.oauthtokencmd":      "story.oauthtokencmd",
+		"story.db.app.passfile":    "story.db.*.passfile",
+		"Story.Remote.Target":      "story.remote.target",
+		"story.issue.tokenfile":    "story.issue.tokenfile",
+		"story.hosteddb.passcmd":   "story.hosteddb.passcmd",
+		"story.branch.maxlength":   "story.branch.maxlength",
+		"story.fetch.singlebranch": "story.fetch.singlebranch",
+	}
+	for name, expected := range cases {
+		key, err := LookupConfigKey(name)
+		testutil.CheckFatal(t, err)
+		if key.Name != expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be `%s` but got `%s`", name, expected, key.Name))
+		}
+	}
+
+	for _, name := range []string{"story.unknown", "story.source.", "story.hostcmd", "story.editorcmd", "story.db.a.b.host"} {
+		if _, err := LookupConfigKey(name); err == nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be unknown", name))
+		}
+	}
+}
+
+func TestConfigKeyValidate(t *testing.T) {
+	valid := map[string]string{
+		"story.fetch.jobs":         "4",
+		"story.fetch.prune":        "yes",
+		"story.fetch.tags":         "None",
+		"story.issueBranchPattern": `^\w+-(\d+)`,
+		"story.hosteddb.driver":    "postgresql",
+		"story.source.default":     "origin/master",
+	}
+	for name, value := range valid {
+		key, err := LookupConfigKey(name)
+		testutil.CheckFatal(t, err)
+		testutil.CheckFatal(t, key.Validate(value))
+	}
+
+	invalid := map[string]string{
+		"story.fetch.jobs":         "four",
+		"story.fetch.prune":        "maybe",
+		"story.fetch.tags":         "some",
+		"story.issueBranchPattern": `(\d+`,
+		"story.hosteddb.driver":    "oracle",
+	}
+	for name, value := range invalid {
+		key, err := LookupConfigKey(name)
+		testutil.CheckFatal(t, err)
+		if err := key.Validate(value); err == nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be invalid for `%s`", value, name))
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package gitutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	git "github.com/libgit2/git2go"
+)
+
+// ConflictSide tells which version of conflicted file to keep
+type ConflictSide int
+
+const (
+	// ConflictOurs keeps version of current branch
+	ConflictOurs ConflictSide = iota
+	// ConflictTheirs keeps version of branch being merged
+	ConflictTheirs
+)
+
+// Conflicts returns conflicted entries of repo index
+func Conflicts(repo *git.Repository) ([]git.IndexConflict, error) {
+
+	index, err := repo.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	if !index.HasConflicts() {
+		return nil, nil
+	}
+
+	it, err := index.ConflictIterator()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to iterate through conflicts\n%+v", err)
+	}
+	defer it.Free()
+
+	var conflicts []git.IndexConflict
+	for {
+		conflict, err := it.Next()
+		if git.IsErrorCode(err, git.ErrIterOver) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts, nil
+}
+
+// ConflictPath returns path of conflicted file
+func ConflictPath(conflict git.IndexConflict) string {
+	for _, entry := range []*git.IndexEntry{conflict.Our, conflict.Their, conflict.Ancestor} {
+		if entry != nil {
+			return entry.Path
+		}
+	}
+	return ""
+}
+
+// ResolveConflict writes chosen version of conflicted file
+// into working tree and stages it
+func ResolveConflict(repo *git.Repository, conflict git.IndexConflict, side ConflictSide) error {
+
+	entry := conflict.Our
+	if side == ConflictTheirs {
+		entry = conflict.Their
+	}
+
+	path := ConflictPath(conflict)
+	fullPath := filepath.Join(repo.Workdir(), path)
+
+	index, err := repo.Index()
+	if err != nil {
+		return err
+	}
+
+	if entry == nil {
+		// chosen side deleted the file
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := index.RemoveConflict(path); err != nil {
+			return err
+		}
+		return index.Write()
+	}
+
+	blob, err := repo.LookupBlob(entry.Id)
+	if err != nil {
+		return err
+	}
+
+	var perm os.FileMode = 0644
+	if entry.Mode == git.FilemodeBlobExecutable {
+		perm = 0755
+	}
+	if err := ioutil.WriteFile(fullPath, blob.Contents(), perm); err != nil {
+		return err
+	}
+
+	return MarkResolved(repo, path)
+}
+
+// MarkResolved stages working tree version of conflicted file
+func MarkResolved(repo *git.Repository, path string) error {
+
+	index, err := repo.Index()
+	if err != nil {
+		return err
+	}
+
+	// adding by path also removes conflict entries of the path
+	if err := index.AddByPath(path); err != nil {
+		return fmt.Errorf("Unable to stage `%s`\n%+v", path, err)
+	}
+
+	return index.Write()
+}
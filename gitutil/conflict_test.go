@@ -0,0 +1,100 @@
+package gitutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+	git "github.com/libgit2/git2go"
+)
+
+func TestResolveConflictsAndCommitMerge(t *testing.T) {
+
+	repo := createTestRepo(t)
+	defer cleanupTestRepo(t, repo)
+
+	repoConfig, err := repo.Config()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repoConfig.SetString("user.name", "Rand Om Hacker"))
+	testutil.CheckFatal(t, repoConfig.SetString("user.email", "random@hacker.com"))
+	defer useRepoConfig(t, repo.Workdir())()
+
+	// both branches change the same files
+	base, _ := seedTestRepo(t, repo)
+	ours := commitTestFile(t, repo, "a.txt", "ours a\n", base)
+	ours = commitTestFile(t, repo, "b.txt", "ours b\n", ours)
+	theirs := commitTestFile(t, repo, "a.txt", "theirs a\n", base)
+	theirs = commitTestFile(t, repo, "b.txt", "theirs b\n", theirs)
+
+	_, err = repo.References.Create("refs/heads/master", ours, true, "")
+	testutil.CheckFatal(t, err)
+	_, err = repo.References.Create("refs/heads/theirs", theirs, true, "")
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repo.CheckoutHead(&git.CheckoutOpts{Strategy: git.CheckoutForce}))
+
+	if err := Pull(repo, "theirs"); err != ErrConflicts {
+		testutil.CheckFatal(t, fmt.Errorf("Expected conflicts but got %v", err))
+	}
+	if source, _ := ConfigString("branch.master.pullsource"); source != "theirs" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected pull source `theirs` to be recorded but got `%s`", source))
+	}
+
+	conflicts, err := Conflicts(repo)
+	testutil.CheckFatal(t, err)
+	if len(conflicts) != 2 {
+		testutil.CheckFatal(t, fmt.Errorf("Expected 2 conflicts but got %d", len(conflicts)))
+	}
+	if err := CommitMerge(repo); err != ErrConflicts {
+		testutil.CheckFatal(t, fmt.Errorf("Expected merge with conflicts left not to be committed, got %v", err))
+	}
+
+	for _, conflict := range conflicts {
+		switch ConflictPath(conflict) {
+		case "a.txt":
+			testutil.CheckFatal(t, ResolveConflict(repo, conflict, ConflictTheirs))
+		case "b.txt":
+			// resolved by hand, like with mergetool
+			err = ioutil.WriteFile(pathInRepo(repo, "b.txt"), []byte("both b\n"), 0644)
+			testutil.CheckFatal(t, err)
+			testutil.CheckFatal(t, MarkResolved(repo, "b.txt"))
+		default:
+			testutil.CheckFatal(t, fmt.Errorf("Unexpected conflict in `%s`", ConflictPath(conflict)))
+		}
+	}
+
+	remaining, err := Conflicts(repo)
+	testutil.CheckFatal(t, err)
+	if len(remaining) != 0 {
+		testutil.CheckFatal(t, fmt.Errorf("Expected every conflict to be resolved but %d are left", len(remaining)))
+	}
+	content, err := ioutil.ReadFile(pathInRepo(repo, "a.txt"))
+	testutil.CheckFatal(t, err)
+	if string(content) != "theirs a\n" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected their version of a.txt but got `%s`", content))
+	}
+
+	testutil.CheckFatal(t, CommitMerge(repo))
+
+	head, err := repo.Head()
+	testutil.CheckFatal(t, err)
+	commit, err := repo.LookupCommit(head.Target())
+	testutil.CheckFatal(t, err)
+	if commit.ParentCount() != 2 || !commit.Parent(0).Id().Equal(ours) || !commit.Parent(1).Id().Equal(theirs) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected merge commit of %s and %s", ours, theirs))
+	}
+	if !strings.Contains(commit.Message(), "'theirs'") {
+		testutil.CheckFatal(t, fmt.Errorf("Expected merge commit to name the source but got `%s`", commit.Message()))
+	}
+	if repo.State() != git.RepositoryStateNone {
+		testutil.CheckFatal(t, fmt.Errorf("Expected merge to be finished"))
+	}
+
+	// pull source is removed, not left empty
+	localConfig, err := repoConfig.OpenLevel(git.ConfigLevelLocal)
+	testutil.CheckFatal(t, err)
+	if _, err := localConfig.LookupString("branch.master.pullsource"); !git.IsErrorCode(err, git.ErrNotFound) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected pull source to be deleted, got %v", err))
+	}
+}
@@ -0,0 +1,343 @@
+package gitutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	git "github.com/libgit2/git2go"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// PassphrasePrompt asks for the passphrase of encrypted private key
+var PassphrasePrompt = readPassphrase
+
+// passphrases caches passphrases of private keys for the rest of the run
+var (
+	passphrases   = make(map[string]string)
+	passphrasesMu sync.Mutex
+)
+
+// sharedCreds is used by CredentialsCallback, which keeps track of a single remote operation
+var (
+	sharedCreds    = &credentials{}
+	sharedCredsURL string
+	sharedCredsMu  sync.Mutex
+)
+
+// defaultSSHKeys are private keys ssh looks for when none is configured
+var defaultSSHKeys = []string{"id_ed25519", "id_ecdsa", "id_rsa", "id_dsa"}
+
+// sshKey is a way of authenticating over ssh
+type sshKey struct {
+	agent      bool
+	publickey  string
+	privatekey string
+}
+
+// credentials keeps track of credentials offered during a single remote operation.
+// libgit2 calls back again whenever the offered credential is rejected,
+// so each call moves on to the next way of authenticating.
+type credentials struct {
+	sshKeys    []sshKey
+	sshAttempt int
+	lastKey    string
+
+	userpass        *gitCredential
+	userpassAttempt int
+}
+
+// maxUserpassAttempts limits how many times credential helpers are asked
+// for https credentials, the second attempt usually prompts the user
+const maxUserpassAttempts = 2
+
+// callback is used as CredentialsCallback of remote operations
+func (c *credentials) callback(url string, usernameFromURL string, allowedTypes git.CredType) (git.ErrorCode, *git.Cred) {
+
+	if allowedTypes&git.CredTypeSshKey != 0 {
+		return c.sshCallback(url, usernameFromURL)
+	}
+
+	if allowedTypes&git.CredTypeUserpassPlaintext != 0 {
+		return c.userpassCallback(url, usernameFromURL)
+	}
+
+	log.Printf("No supported credential type for `%s`", url)
+	return git.ErrAuth, &git.Cred{}
+}
+
+// NewCredentialsCallback returns callback offering the same credentials remote operations
+// of gitutil do, for callers setting up their own git.RemoteCallbacks.
+// Use a new callback for every remote operation, since it goes on from the last
+// credential it offered.
+func NewCredentialsCallback() git.CredentialsCallback {
+	return (&credentials{}).callback
+}
+
+// CredentialsCallback is a callback of NewCredentialsCallback shared by every caller.
+// It is meant for a single remote operation, starting over only with another url
+// or once every way of authenticating was rejected. Following operations to the same
+// url would go on past the credential that worked, so use NewCredentialsCallback instead.
+//
+// Deprecated: use NewCredentialsCallback for every remote operation.
+func CredentialsCallback(url string, username string, allowedTypes git.CredType) (git.ErrorCode, *git.Cred) {
+
+	sharedCredsMu.Lock()
+	defer sharedCredsMu.Unlock()
+
+	if url != sharedCredsURL {
+		sharedCreds, sharedCredsURL = &credentials{}, url
+	}
+
+	ret, cred := sharedCreds.callback(url, username, allowedTypes)
+	if ret != git.ErrOk {
+		sharedCreds = &credentials{}
+	}
+
+	return ret, cred
+}
+
+// done reports result of the remote operation to credential helpers
+func (c *credentials) done(err error) {
+
+	if c.userpass == nil {
+		return
+	}
+
+	if err == nil {
+		if err := c.userpass.approve(); err != nil {
+			log.Println(err)
+		}
+	} else if git.IsErrorCode(err, git.ErrAuth) {
+		if err := c.userpass.reject(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// userpassCallback gets username and password or token from `git credential`
+func (c *credentials) userpassCallback(url string, usernameFromURL string) (git.ErrorCode, *git.Cred) {
+
+	// previous credential was rejected by the server
+	if c.userpass != nil {
+		if err := c.userpass.reject(); err != nil {
+			log.Println(err)
+		}
+		c.userpass = nil
+	}
+
+	if c.userpassAttempt >= maxUserpassAttempts {
+		log.Printf("Unable to authenticate to `%s` with provided credentials", url)
+		return git.ErrAuth, &git.Cred{}
+	}
+	c.userpassAttempt++
+
+	userpass, err := credentialFromURL(url, usernameFromURL)
+	if err != nil {
+		log.Println(err)
+		return git.ErrAuth, &git.Cred{}
+	}
+	if err := userpass.fill(); err != nil {
+		log.Println(err)
+		return git.ErrAuth, &git.Cred{}
+	}
+	c.userpass = userpass
+
+	ret, cred := git.NewCredUserpassPlaintext(userpass.Username, userpass.Password)
+	return git.ErrorCode(ret), &cred
+}
+
+// sshCallback offers the next ssh credential to try
+func (c *credentials) sshCallback(url string, usernameFromURL string) (git.ErrorCode, *git.Cred) {
+
+	// previous key was rejected, so its passphrase should not be reused
+	if c.lastKey != "" {
+		forgetPassphrase(c.lastKey)
+		c.lastKey = ""
+	}
+
+	if c.sshKeys == nil {
+		c.sshKeys = sshKeyCandidates()
+	}
+
+	username := sshUsername(url, usernameFromURL)
+	for c.sshAttempt < len(c.sshKeys) {
+		key := c.sshKeys[c.sshAttempt]
+		c.sshAttempt++
+
+		if key.agent {
+			ret, cred := git.NewCredSshKeyFromAgent(username)
+			return git.ErrorCode(ret), &cred
+		}
+
+		passphrase, err := keyPassphrase(key.privatekey)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if passphrase != "" {
+			c.lastKey = key.privatekey
+		}
+
+		ret, cred := git.NewCredSshKey(username, key.publickey, key.privatekey, passphrase)
+		return git.ErrorCode(ret), &cred
+	}
+
+	log.Printf("Unable to authenticate as `%s` to `%s` with ssh-agent or any ssh key", username, url)
+	return git.ErrAuth, &git.Cred{}
+}
+
+// sshKeyCandidates lists ways of authenticating over ssh in the order they are tried:
+// ssh-agent, keys from `story.ssh.*` config, then default keys in ~/.ssh
+func sshKeyCandidates() []sshKey {
+
+	var keys []sshKey
+
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		keys = append(keys, sshKey{agent: true})
+	}
+
+	// Get path to public/private keys from config
+	privatekey, _ := ConfigString("story.ssh.privatekey")
+	if privatekey != "" {
+		publickey, _ := ConfigString("story.ssh.publickey")
+		keys = append(keys, sshKey{publickey: publickey, privatekey: privatekey})
+	}
+
+	home := os.Getenv("HOME")
+	for _, name := range defaultSSHKeys {
+		path := filepath.Join(home, ".ssh", name)
+		if path == privatekey {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		key := sshKey{privatekey: path}
+		if _, err := os.Stat(path + ".pub"); err == nil {
+			key.publickey = path + ".pub"
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// sshUsername returns user to authenticate as, preferring the one in remote url
+func sshUsername(remoteURL string, usernameFromURL string) string {
+
+	if usernameFromURL != "" {
+		return usernameFromURL
+	}
+
+	if u, err := url.Parse(remoteURL); err == nil && u.User != nil && u.User.Username() != "" {
+		return u.User.Username()
+	}
+
+	// scp-like syntax, user@host:path
+	if at := strings.Index(remoteURL, "@"); at > 0 && !strings.Contains(remoteURL[:at], "/") {
+		return remoteURL[:at]
+	}
+
+	return "git"
+}
+
+// keyPassphrase returns passphrase of given private key,
+// asking for it once if the key is encrypted
+func keyPassphrase(privatekey string) (string, error) {
+
+	encrypted, err := keyIsEncrypted(privatekey)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read ssh key `%s`\n%+v", privatekey, err)
+	}
+	if !encrypted {
+		return "", nil
+	}
+
+	passphrasesMu.Lock()
+	defer passphrasesMu.Unlock()
+
+	if passphrase, ok := passphrases[privatekey]; ok {
+		return passphrase, nil
+	}
+
+	passphrase, err := PassphrasePrompt(privatekey)
+	if err != nil {
+		return "", fmt.Errorf("Unable to get passphrase for `%s`\n%+v", privatekey, err)
+	}
+	passphrases[privatekey] = passphrase
+
+	return passphrase, nil
+}
+
+func forgetPassphrase(privatekey string) {
+	passphrasesMu.Lock()
+	defer passphrasesMu.Unlock()
+	delete(passphrases, privatekey)
+}
+
+// keyIsEncrypted tells whether private key file is protected by passphrase
+func keyIsEncrypted(privatekey string) (bool, error) {
+
+	data, err := ioutil.ReadFile(privatekey)
+	if err != nil {
+		return false, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false, fmt.Errorf("`%s` is not a PEM encoded key", privatekey)
+	}
+
+	// legacy PEM keys carry encryption info in headers
+	if strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") || block.Type == "ENCRYPTED PRIVATE KEY" {
+		return true, nil
+	}
+
+	if block.Type != "OPENSSH PRIVATE KEY" {
+		return false, nil
+	}
+
+	// openssh keys start with magic followed by cipher name
+	magic := []byte("openssh-key-v1\x00")
+	if !bytes.HasPrefix(block.Bytes, magic) {
+		return false, fmt.Errorf("`%s` is not a valid openssh key", privatekey)
+	}
+	rest := block.Bytes[len(magic):]
+	if len(rest) < 4 {
+		return false, fmt.Errorf("`%s` is not a valid openssh key", privatekey)
+	}
+	length := binary.BigEndian.Uint32(rest)
+	if uint32(len(rest)-4) < length {
+		return false, fmt.Errorf("`%s` is not a valid openssh key", privatekey)
+	}
+
+	return string(rest[4:4+length]) != "none", nil
+}
+
+// readPassphrase reads passphrase from terminal without echoing it
+func readPassphrase(privatekey string) (string, error) {
+
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(os.Stderr, "Enter passphrase for key '%s': ", privatekey)
+	passphrase, err := terminal.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(passphrase), nil
+}
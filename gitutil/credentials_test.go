@@ -0,0 +1,126 @@
+package gitutil
+
+import (
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+	git "github.com/libgit2/git2go"
+)
+
+func TestSSHUsername(t *testing.T) {
+
+	cases := []struct {
+		url      string
+		fromURL  string
+		expected string
+	}{
+		{"git@github.com:kidonchu/gitcli.git", "", "git"},
+		{"deploy@example.com:repo.git", "", "deploy"},
+		{"ssh://builder@example.com:2222/repo.git", "", "builder"},
+		{"ssh://example.com/repo.git", "", "git"},
+		{"ssh://example.com/repo.git", "kchu", "kchu"},
+	}
+
+	for _, c := range cases {
+		result := sshUsername(c.url, c.fromURL)
+		if result != c.expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` for `%s` but got `%s`", c.expected, c.url, result))
+		}
+	}
+}
+
+func TestKeyIsEncrypted(t *testing.T) {
+
+	cases := []struct {
+		block     *pem.Block
+		encrypted bool
+	}{
+		{&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("key")}, false},
+		{&pem.Block{
+			Type:    "RSA PRIVATE KEY",
+			Headers: map[string]string{"Proc-Type": "4,ENCRYPTED", "DEK-Info": "AES-128-CBC,00"},
+			Bytes:   []byte("key"),
+		}, true},
+		{&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: opensshKey("none")}, false},
+		{&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: opensshKey("aes256-ctr")}, true},
+	}
+
+	for i, c := range cases {
+		f, err := ioutil.TempFile("", "gitcli")
+		testutil.CheckFatal(t, err)
+		defer os.Remove(f.Name())
+
+		err = pem.Encode(f, c.block)
+		testutil.CheckFatal(t, err)
+		f.Close()
+
+		encrypted, err := keyIsEncrypted(f.Name())
+		testutil.CheckFatal(t, err)
+		if encrypted != c.encrypted {
+			testutil.CheckFatal(t, fmt.Errorf("Expected case %d encrypted to be %t but got %t", i, c.encrypted, encrypted))
+		}
+	}
+}
+
+// opensshKey builds the beginning of openssh key with given cipher
+func opensshKey(cipher string) []byte {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(cipher)))
+	key := append([]byte("openssh-key-v1\x00"), length...)
+	return append(key, []byte(cipher)...)
+}
+
+func TestCredentialsCallbackPerOperation(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+	defer useRepoConfig(t, repo.Workdir())()
+
+	home, err := ioutil.TempDir("", "gitcli")
+	testutil.CheckFatal(t, err)
+	defer os.RemoveAll(home)
+	testutil.CheckFatal(t, os.Mkdir(filepath.Join(home, ".ssh"), 0700))
+
+	// two encrypted keys, the first of which works
+	encrypted := &pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: opensshKey("aes256-ctr")}
+	for _, name := range []string{"id_ed25519", "id_rsa"} {
+		err = ioutil.WriteFile(filepath.Join(home, ".ssh", name), pem.EncodeToMemory(encrypted), 0600)
+		testutil.CheckFatal(t, err)
+	}
+	first := filepath.Join(home, ".ssh", "id_ed25519")
+	defer forgetPassphrase(first)
+	defer forgetPassphrase(filepath.Join(home, ".ssh", "id_rsa"))
+
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("HOME", home)
+	os.Unsetenv("SSH_AUTH_SOCK")
+
+	var prompted []string
+	defer func(prompt func(string) (string, error)) { PassphrasePrompt = prompt }(PassphrasePrompt)
+	PassphrasePrompt = func(privatekey string) (string, error) {
+		prompted = append(prompted, privatekey)
+		return "secret", nil
+	}
+
+	// two fetches in a row, each authenticated by the first key
+	for i := 0; i < 2; i++ {
+		callback := NewCredentialsCallback()
+		ret, _ := callback("git@example.com:repo.git", "git", git.CredTypeSshKey)
+		if ret != git.ErrOk {
+			testutil.CheckFatal(t, fmt.Errorf("Expected operation %d to get a credential but got %v", i, ret))
+		}
+	}
+
+	if len(prompted) != 1 || prompted[0] != first {
+		testutil.CheckFatal(t, fmt.Errorf("Expected passphrase of `%s` to be asked once but got %v", first, prompted))
+	}
+	if _, ok := passphrases[first]; !ok {
+		testutil.CheckFatal(t, fmt.Errorf("Expected passphrase of `%s` to be kept", first))
+	}
+}
@@ -0,0 +1,130 @@
+package gitutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// gitCredential is a credential exchanged with `git credential`,
+// so that whatever helper is configured in git (osxkeychain, cache, store, ...)
+// provides username and password or token for https remotes
+type gitCredential struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// credentialFromURL describes credential needed for given remote url
+func credentialFromURL(remoteURL string, username string) (*gitCredential, error) {
+
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse remote url `%s`\n%+v", remoteURL, err)
+	}
+
+	cred := &gitCredential{
+		Protocol: u.Scheme,
+		Host:     u.Host,
+		Path:     strings.TrimPrefix(u.Path, "/"),
+		Username: username,
+	}
+	if cred.Username == "" && u.User != nil {
+		cred.Username = u.User.Username()
+	}
+
+	return cred, nil
+}
+
+// encode formats credential in the `key=value` format `git credential` reads
+func (c *gitCredential) encode() string {
+
+	var buf bytes.Buffer
+	for _, attr := range [][2]string{
+		{"protocol", c.Protocol},
+		{"host", c.Host},
+		{"path", c.Path},
+		{"username", c.Username},
+		{"password", c.Password},
+	} {
+		if attr[1] != "" {
+			fmt.Fprintf(&buf, "%s=%s\n", attr[0], attr[1])
+		}
+	}
+	buf.WriteString("\n")
+
+	return buf.String()
+}
+
+// decode reads `key=value` output of `git credential fill` into credential
+func (c *gitCredential) decode(output string) {
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "protocol":
+			c.Protocol = parts[1]
+		case "host":
+			c.Host = parts[1]
+		case "path":
+			c.Path = parts[1]
+		case "username":
+			c.Username = parts[1]
+		case "password":
+			c.Password = parts[1]
+		}
+	}
+}
+
+// fill asks configured credential helpers, or the user, for username and password
+func (c *gitCredential) fill() error {
+
+	output, err := runGitCredential("fill", c)
+	if err != nil {
+		return err
+	}
+	c.decode(output)
+
+	if c.Password == "" {
+		return fmt.Errorf("No password or token provided for `%s://%s`", c.Protocol, c.Host)
+	}
+
+	return nil
+}
+
+// approve tells credential helpers the credential worked, so they can store it
+func (c *gitCredential) approve() error {
+	_, err := runGitCredential("approve", c)
+	return err
+}
+
+// reject tells credential helpers the credential did not work, so they can forget it
+func (c *gitCredential) reject() error {
+	_, err := runGitCredential("reject", c)
+	return err
+}
+
+func runGitCredential(action string, c *gitCredential) (string, error) {
+
+	var stdout bytes.Buffer
+	cmd := exec.Command("git", "credential", action)
+	cmd.Stdin = strings.NewReader(c.encode())
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Unable to run `git credential %s` for `%s://%s`\n%+v", action, c.Protocol, c.Host, err)
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,32 @@
+package gitutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestCredentialFromURL(t *testing.T) {
+
+	cred, err := credentialFromURL("https://kchu@github.com/kidonchu/gitcli.git", "")
+	testutil.CheckFatal(t, err)
+
+	expected := "protocol=https\nhost=github.com\npath=kidonchu/gitcli.git\nusername=kchu\n\n"
+	if cred.encode() != expected {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `%q` but got `%q`", expected, cred.encode()))
+	}
+}
+
+func TestCredentialDecode(t *testing.T) {
+
+	cred := &gitCredential{Protocol: "https", Host: "github.com"}
+	cred.decode("protocol=https\nhost=github.com\nusername=kchu\npassword=s3cr=t\n")
+
+	if cred.Username != "kchu" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected username `kchu` but got `%s`", cred.Username))
+	}
+	if cred.Password != "s3cr=t" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected password `s3cr=t` but got `%s`", cred.Password))
+	}
+}
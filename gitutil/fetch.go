@@ -0,0 +1,184 @@
+package gitutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	git "github.com/libgit2/git2go"
+)
+
+// FetchErrors collects failures of fetching several remotes, by remote name
+type FetchErrors map[string]error
+
+func (errs FetchErrors) Error() string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{fmt.Sprintf("Unable to fetch %d remotes:", len(errs))}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, strings.TrimSpace(errs[name].Error())))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FetchSources fetches remotes of given sources in `<remote>/<branch>` format.
+// When `story.fetch.singlebranch` is set, only the source branches are fetched
+// instead of every branch of their remotes.
+func FetchSources(repo *git.Repository, sources []string) error {
+
+	singleBranch, _ := ConfigBool("story.fetch.singlebranch")
+
+	// group branches by remote, keeping order of remotes
+	var remoteNames []string
+	refspecs := make(map[string][]string)
+	for _, source := range sources {
+		remoteName, branchName := SplitSource(source)
+		if _, ok := refspecs[remoteName]; !ok {
+			remoteNames = append(remoteNames, remoteName)
+			refspecs[remoteName] = []string{}
+		}
+		if singleBranch {
+			refspecs[remoteName] = append(refspecs[remoteName], BranchRefspec(remoteName, branchName))
+		}
+	}
+
+	errs := make(FetchErrors)
+	for _, remoteName := range remoteNames {
+		if singleBranch {
+			fmt.Printf("Fetching %v from remote: `%s`\n", refspecs[remoteName], remoteName)
+		} else {
+			fmt.Printf("Fetching most recent with remote: `%s`\n", remoteName)
+		}
+
+		params := fetchParams{
+			refspecs:     refspecs[remoteName],
+			prune:        fetchPruneConfig(),
+			showProgress: true,
+		}
+		if err := fetchRemote(repo, remoteName, params); err != nil {
+			errs[remoteName] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// SplitSource splits source in `<remote>/<branch>` format into remote and branch names,
+// defaulting remote to origin
+func SplitSource(source string) (string, string) {
+	parts := strings.SplitN(source, "/", 2)
+	if len(parts) == 1 {
+		return "origin", parts[0]
+	}
+	return parts[0], parts[1]
+}
+
+// BranchRefspec returns refspec fetching only given branch of remote
+func BranchRefspec(remoteName string, branchName string) string {
+	return fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branchName, remoteName, branchName)
+}
+
+// fetchPruneConfig tells whether fetch should prune, from `story.fetch.prune`
+func fetchPruneConfig() bool {
+	prune, _ := ConfigBool("story.fetch.prune")
+	return prune
+}
+
+// fetchTagsConfig returns tag download mode in `story.fetch.tags`, one of auto, none or all
+func fetchTagsConfig() git.DownloadTags {
+	tags, _ := ConfigString("story.fetch.tags")
+	switch strings.ToLower(tags) {
+	case "none":
+		return git.DownloadTagsNone
+	case "all":
+		return git.DownloadTagsAll
+	case "auto":
+		return git.DownloadTagsAuto
+	}
+	return git.DownloadTagsUnspecified
+}
+
+// FetchAll fetches given remotes concurrently, at most `jobs` at a time.
+// Each remote is given up on after `timeout`, and failures are returned together as FetchErrors.
+func FetchAll(repo *git.Repository, remoteNames []string, jobs int, timeout time.Duration, prune bool) error {
+
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = make(FetchErrors)
+		sem  = make(chan struct{}, jobs)
+	)
+
+	for _, name := range remoteNames {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+
+			start := time.Now()
+			err := fetchWithTimeout(repo.Path(), name, timeout, prune, func() { <-sem })
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[name] = err
+				return
+			}
+			fmt.Printf("\tFetch: `%s` done in %s\n", name, time.Since(start)/time.Millisecond*time.Millisecond)
+		}(name)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// fetchWithTimeout fetches remote with its own repository instance,
+// since libgit2 objects should not be shared between threads.
+// release is called once libgit2 is done with the remote, which can be after timing out.
+func fetchWithTimeout(path string, remoteName string, timeout time.Duration, prune bool, release func()) error {
+
+	result := make(chan error, 1)
+	go func() {
+		defer release()
+
+		repo, err := git.OpenRepository(path)
+		if err != nil {
+			result <- err
+			return
+		}
+		defer repo.Free()
+
+		result <- fetchRemote(repo, remoteName, fetchParams{
+			prune:    prune,
+			deadline: time.Now().Add(timeout),
+		})
+	}()
+
+	// libgit2 only notices the deadline in callbacks, which are not called
+	// while connecting, so stop waiting for it here as well. The fetch keeps
+	// its job slot until it gives up, so no more than `jobs` run at a time.
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("Timed out after %s", timeout)
+	}
+}
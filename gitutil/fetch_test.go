@@ -0,0 +1,47 @@
+package gitutil
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestFetchAll(t *testing.T) {
+
+	// Prepare remote repo with a commit on master
+	remoteRepo := createBareTestRepo(t)
+	defer cleanupTestRepo(t, remoteRepo)
+	seededRepo := createTestRepo(t)
+	defer cleanupTestRepo(t, seededRepo)
+	remote, err := seededRepo.Remotes.Create("test_fetch", remoteRepo.Path())
+	testutil.CheckFatal(t, err)
+	seedTestRepo(t, seededRepo)
+	err = Push(seededRepo, remote, "refs/heads/master")
+	testutil.CheckFatal(t, err)
+
+	localRepo := createTestRepo(t)
+	defer cleanupTestRepo(t, localRepo)
+	_, err = localRepo.Remotes.Create("test_fetch", remoteRepo.Path())
+	testutil.CheckFatal(t, err)
+
+	err = FetchAll(localRepo, []string{"test_fetch", "test_missing"}, 2, 10*time.Second, false)
+	errs, ok := err.(FetchErrors)
+	if !ok {
+		testutil.CheckFatal(t, fmt.Errorf("Expected FetchErrors but got %+v", err))
+	}
+	if len(errs) != 1 || errs["test_missing"] == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected only `test_missing` to fail but got %+v", errs))
+	}
+
+	_, err = localRepo.References.Lookup("refs/remotes/test_fetch/master")
+	testutil.CheckFatal(t, err)
+}
+
+func TestBranchRefspec(t *testing.T) {
+	expected := "+refs/heads/feature/foo:refs/remotes/upstream/feature/foo"
+	if result := BranchRefspec("upstream", "feature/foo"); result != expected {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", expected, result))
+	}
+}
@@ -3,94 +3,244 @@ package gitutil
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
 
 	git "github.com/libgit2/git2go"
 )
 
 var (
-	globalConfig *git.Config
-	localConfig  *git.Config
+	// config is git config as git itself resolves it: system, XDG, global and repository
+	// files, along with files they include, later ones taking precedence
+	config *git.Config
+	// shared is team-shared config of repository, under every git config file
+	shared *SharedConfig
+	// repoPath is git directory of repository config was opened for
+	repoPath string
+	// configMu guards opening config, as remote callbacks read it from several goroutines
+	configMu sync.Mutex
 )
 
-// initConfig initializes git config object from local .git/config file
-// scope can be either "global" or "local"
+// ConfigValue is config value along with where it is set
+type ConfigValue struct {
+	Name  string
+	Value string
+	// Origin is one of system, xdg, global, local or shared
+	Origin string
+	// File value is read from, or the file including it
+	File string
+}
+
+// initConfig opens config of repository containing working directory.
+// Outside of repository, only system, XDG and global config are used.
 func initConfig() error {
 
-	if configPath, err := git.ConfigFindGlobal(); err == nil {
-		globalConfig, _ = git.OpenOndisk(nil, configPath)
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if config != nil {
+		return nil
 	}
 
-	if dir, err := os.Getwd(); err == nil {
-		configPath := fmt.Sprintf("%s/.git/config", dir)
-		localConfig, _ = git.OpenOndisk(nil, configPath)
+	repo, repoErr := OpenRepo()
+
+	var err error
+	if repoErr == nil {
+		config, err = repo.Config()
+	} else {
+		config, err = git.OpenDefault()
+	}
+	if err != nil {
+		config = nil
+		return fmt.Errorf("Unable to open git config\n%+v", err)
 	}
 
-	if !hasConfig() {
-		return fmt.Errorf("Could not find any git config file")
+	if repoErr == nil {
+		repoPath = repo.Path()
+		if !repo.IsBare() {
+			// a broken shared file must not keep personal config from working
+			shared, err = ReadSharedConfig(repo.Workdir())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Ignoring shared config: %v\n", err)
+			}
+		}
 	}
 
 	return nil
 }
 
-func hasConfig() bool {
-	return globalConfig != nil || localConfig != nil
-}
-
-// ConfigString finds string value from git config
+// ConfigString finds string value from git config, then from shared config
 func ConfigString(name string) (string, error) {
 
-	// Check if config has already been initialized
-	if !hasConfig() {
-		if err := initConfig(); err != nil {
-			return "", err
-		}
+	if err := initConfig(); err != nil {
+		return "", err
 	}
 
-	if result, err := localConfig.LookupString(name); err == nil {
+	if result, err := config.LookupString(name); err == nil {
 		return result, nil
 	}
 
-	// fall back to global config if not found in local config
-	if result, err := globalConfig.LookupString(name); err == nil {
+	if result, ok := shared.lookup(name); ok {
 		return result, nil
 	}
 
 	return "", fmt.Errorf("No result found in git config files for `%s`", name)
 }
 
-// ConfigInt32 finds string value from git config
+// ConfigInt32 finds string value from git config, then from shared config
 func ConfigInt32(name string) (int32, error) {
 
-	// Check if config has already been initialized
-	if !hasConfig() {
-		if err := initConfig(); err != nil {
-			return 0, err
+	if err := initConfig(); err != nil {
+		return 0, err
+	}
+
+	if result, err := config.LookupInt32(name); err == nil {
+		return result, nil
+	}
+
+	if value, ok := shared.lookup(name); ok {
+		result, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("`%s` in `%s` is not a number", name, shared.Path)
 		}
+		return int32(result), nil
 	}
 
-	if result, err := localConfig.LookupInt32(name); err == nil {
+	return 0, fmt.Errorf("No result found in git config files for `%s`", name)
+}
+
+// ConfigBool finds bool value from git config, then from shared config
+func ConfigBool(name string) (bool, error) {
+
+	if err := initConfig(); err != nil {
+		return false, err
+	}
+
+	if result, err := config.LookupBool(name); err == nil {
 		return result, nil
 	}
 
-	// fall back to global config if not found in local config
-	if result, err := globalConfig.LookupInt32(name); err == nil {
+	if value, ok := shared.lookup(name); ok {
+		result, err := parseConfigBool(value)
+		if err != nil {
+			return false, fmt.Errorf("`%s` in `%s` is not true or false", name, shared.Path)
+		}
 		return result, nil
 	}
 
-	return 0, fmt.Errorf("No result found in git config files for `%s`", name)
+	return false, fmt.Errorf("No result found in git config files for `%s`", name)
 }
 
-// SetConfigString sets string value to git config
-func SetConfigString(name, value string) error {
+// ConfigEntries finds every config whose name matches given regex,
+// with the value taking precedence, as in ConfigString
+func ConfigEntries(pattern string) (map[string]string, error) {
+
+	values, err := ConfigValues(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]string)
+	for _, value := range values {
+		entries[value.Name] = value.Value
+	}
+
+	return entries, nil
+}
+
+// ConfigValues finds every config whose name matches given regex, in name order,
+// along with where value taking precedence is set
+func ConfigValues(pattern string) ([]ConfigValue, error) {
+
+	if err := initConfig(); err != nil {
+		return nil, err
+	}
 
-	// Check if config has already been initialized
-	if !hasConfig() {
-		if err := initConfig(); err != nil {
-			return err
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to iterate through config `%s`\n%+v", pattern, err)
+	}
+
+	found := make(map[string]ConfigValue)
+	if shared != nil {
+		for name, value := range shared.Values {
+			if regex.MatchString(name) {
+				found[name] = ConfigValue{Name: name, Value: value, Origin: "shared", File: shared.Path}
+			}
 		}
 	}
 
-	err := localConfig.SetString(name, value)
+	it, err := config.NewIteratorGlob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to iterate through config `%s`\n%+v", pattern, err)
+	}
+	defer it.Free()
+
+	levels := make(map[string]git.ConfigLevel)
+	for {
+		entry, err := it.Next()
+		if err != nil {
+			break
+		}
+		// entries are not ordered by level, and a file can set the same name more than once
+		if level, seen := levels[entry.Name]; seen && entry.Level < level {
+			continue
+		}
+		levels[entry.Name] = entry.Level
+		origin, file := configLevelOrigin(entry.Level)
+		found[entry.Name] = ConfigValue{Name: entry.Name, Value: entry.Value, Origin: origin, File: file}
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]ConfigValue, len(names))
+	for i, name := range names {
+		values[i] = found[name]
+	}
+
+	return values, nil
+}
+
+// configLevelOrigin names config level and its file
+func configLevelOrigin(level git.ConfigLevel) (string, string) {
+
+	var origin, file string
+	switch level {
+	case git.ConfigLevelSystem:
+		origin = "system"
+		file, _ = git.ConfigFindSystem()
+	case git.ConfigLevelXDG:
+		origin = "xdg"
+		file, _ = git.ConfigFindXDG()
+	case git.ConfigLevelGlobal:
+		origin = "global"
+		file, _ = git.ConfigFindGlobal()
+	case git.ConfigLevelLocal:
+		origin = "local"
+		file = filepath.Join(repoPath, "config")
+	default:
+		origin = fmt.Sprintf("level %d", level)
+	}
+
+	return origin, file
+}
+
+// SetConfigString sets string value to git config of repository,
+// or global config outside of repository
+func SetConfigString(name, value string) error {
+
+	if err := initConfig(); err != nil {
+		return err
+	}
+
+	err := config.SetString(name, value)
 	if err != nil {
 		return fmt.Errorf("Unable to set string config `%s` to `%s`\n%+v", name, value, err)
 	}
@@ -98,36 +248,53 @@ func SetConfigString(name, value string) error {
 	return nil
 }
 
-// SetConfigInt32 sets int32 value to git config
+// SetConfigInt32 sets int32 value to git config of repository,
+// or global config outside of repository
 func SetConfigInt32(name string, value int32) error {
 
-	// Check if config has already been initialized
-	if !hasConfig() {
-		if err := initConfig(); err != nil {
-			return err
-		}
+	if err := initConfig(); err != nil {
+		return err
 	}
 
-	err := localConfig.SetInt32(name, value)
+	err := config.SetInt32(name, value)
 	if err != nil {
-		return fmt.Errorf("No result found in gitconfig for `%s`", name)
+		return fmt.Errorf("Unable to set int config `%s` to `%d`\n%+v", name, value, err)
 	}
 
 	return nil
 }
 
-// DeleteConfig deletes config
+// DeleteConfig deletes config from every git config file setting it, repository, global and
+// the rest, so that no value of it is left, much like `git config --unset` on each of them.
+// Values in shared config are left alone.
 func DeleteConfig(name string) error {
 
-	// Check if config has already been initialized
-	if !hasConfig() {
-		if err := initConfig(); err != nil {
-			return err
-		}
+	if err := initConfig(); err != nil {
+		return err
+	}
+
+	levels := []git.ConfigLevel{
+		git.ConfigLevelSystem,
+		git.ConfigLevelXDG,
+		git.ConfigLevelGlobal,
+		git.ConfigLevelLocal,
+		git.ConfigLevelApp,
 	}
+	for _, level := range levels {
+		// levels without a file are not opened
+		levelConfig, err := config.OpenLevel(level)
+		if err != nil {
+			continue
+		}
 
-	globalConfig.Delete(name)
-	localConfig.Delete(name)
+		// nothing to delete is fine
+		err = levelConfig.Delete(name)
+		levelConfig.Free()
+		if err != nil && !git.IsErrorCode(err, git.ErrNotFound) {
+			origin, _ := configLevelOrigin(level)
+			return fmt.Errorf("Unable to delete config `%s` from %s config\n%+v", name, origin, err)
+		}
+	}
 
 	return nil
 }
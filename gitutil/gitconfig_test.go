@@ -2,12 +2,20 @@ package gitutil
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/kidonchu/gitcli/testutil"
+	git "github.com/libgit2/git2go"
 )
 
 func TestConfigString(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+	defer useRepoConfig(t, repo.Workdir())()
+
 	// Setting string configuration
 	err := SetConfigString("string.foo", "bar")
 	if err != nil {
@@ -28,6 +36,10 @@ func TestConfigString(t *testing.T) {
 }
 
 func TestConfigInt32(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+	defer useRepoConfig(t, repo.Workdir())()
+
 	// Setting string configuration
 	err := SetConfigInt32("int.foo", 1234)
 	if err != nil {
@@ -46,3 +58,223 @@ func TestConfigInt32(t *testing.T) {
 		testutil.CheckFatal(t, err)
 	}
 }
+
+func TestConfigBool(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+	defer useRepoConfig(t, repo.Workdir())()
+
+	// Setting bool configuration as string
+	err := SetConfigString("bool.foo", "true")
+	if err != nil {
+		testutil.CheckFatal(t, err)
+	}
+
+	result, err := ConfigBool("bool.foo")
+	if err != nil {
+		testutil.CheckFatal(t, err)
+	}
+	if !result {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `true` but got `%t`", result))
+	}
+
+	if err = DeleteConfig("bool.foo"); err != nil {
+		testutil.CheckFatal(t, err)
+	}
+}
+
+func TestGetStoryDbs(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+	defer useRepoConfig(t, repo.Workdir())()
+
+	cases := map[string]int{
+		"":              0,
+		"app_foo,":      1,
+		"app_foo,db2:x": 2,
+	}
+
+	for value, expected := range cases {
+		err := SetConfigString("branch.storydbs-test.storydbs", value)
+		testutil.CheckFatal(t, err)
+
+		dbs, err := GetStoryDbs("storydbs-test")
+		testutil.CheckFatal(t, err)
+		if len(dbs) != expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected %d databases in `%s` but got %q", expected, value, dbs))
+		}
+	}
+
+	if err := DeleteConfig("branch.storydbs-test.storydbs"); err != nil {
+		testutil.CheckFatal(t, err)
+	}
+}
+
+func TestDeleteConfigEveryLevel(t *testing.T) {
+	home, err := ioutil.TempDir("", "gitcli")
+	testutil.CheckFatal(t, err)
+	defer os.RemoveAll(home)
+
+	globalFile := filepath.Join(home, ".gitconfig")
+	err = ioutil.WriteFile(globalFile, []byte("[string]\n\teverywhere = from-global\n"), 0644)
+	testutil.CheckFatal(t, err)
+
+	globalPath, err := git.SearchPath(git.ConfigLevelGlobal)
+	testutil.CheckFatal(t, err)
+	defer git.SetSearchPath(git.ConfigLevelGlobal, globalPath)
+	testutil.CheckFatal(t, git.SetSearchPath(git.ConfigLevelGlobal, home))
+
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+
+	repoConfig, err := repo.Config()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repoConfig.SetString("string.everywhere", "from-repo"))
+
+	defer useRepoConfig(t, repo.Workdir())()
+
+	testutil.CheckFatal(t, DeleteConfig("string.everywhere"))
+
+	// global value must not show up once repository one is gone
+	if result, err := ConfigString("string.everywhere"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected config to be deleted but got `%s`", result))
+	}
+	globalConfig, err := git.OpenOndisk(nil, globalFile)
+	testutil.CheckFatal(t, err)
+	if _, err := globalConfig.LookupString("string.everywhere"); !git.IsErrorCode(err, git.ErrNotFound) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected config to be deleted from global config, got %v", err))
+	}
+
+	// deleting again is fine
+	testutil.CheckFatal(t, DeleteConfig("string.everywhere"))
+}
+
+func TestConfigInSubdirectory(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+
+	repoConfig, err := repo.Config()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repoConfig.SetString("string.subdir", "from-repo"))
+
+	subdir := filepath.Join(repo.Workdir(), "a", "b")
+	testutil.CheckFatal(t, os.MkdirAll(subdir, 0755))
+
+	defer useRepoConfig(t, subdir)()
+
+	found, err := OpenRepo()
+	testutil.CheckFatal(t, err)
+	if found.Path() != repo.Path() {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", repo.Path(), found.Path()))
+	}
+
+	result, err := ConfigString("string.subdir")
+	testutil.CheckFatal(t, err)
+	if result != "from-repo" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `from-repo` but got `%s`", result))
+	}
+}
+
+func TestConfigGitDir(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+
+	repoConfig, err := repo.Config()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repoConfig.SetString("string.gitdir", "from-git-dir"))
+
+	elsewhere, err := ioutil.TempDir("", "gitcli")
+	testutil.CheckFatal(t, err)
+	defer os.RemoveAll(elsewhere)
+
+	defer os.Setenv("GIT_DIR", os.Getenv("GIT_DIR"))
+	os.Setenv("GIT_DIR", repo.Path())
+	defer useRepoConfig(t, elsewhere)()
+
+	found, err := OpenRepo()
+	testutil.CheckFatal(t, err)
+	if found.Path() != repo.Path() {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", repo.Path(), found.Path()))
+	}
+
+	result, err := ConfigString("string.gitdir")
+	testutil.CheckFatal(t, err)
+	if result != "from-git-dir" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `from-git-dir` but got `%s`", result))
+	}
+}
+
+func TestConfigOutsideRepository(t *testing.T) {
+	home, err := ioutil.TempDir("", "gitcli")
+	testutil.CheckFatal(t, err)
+	defer os.RemoveAll(home)
+
+	err = ioutil.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[string]\n\toutside = from-global\n"), 0644)
+	testutil.CheckFatal(t, err)
+
+	globalPath, err := git.SearchPath(git.ConfigLevelGlobal)
+	testutil.CheckFatal(t, err)
+	defer git.SetSearchPath(git.ConfigLevelGlobal, globalPath)
+	testutil.CheckFatal(t, git.SetSearchPath(git.ConfigLevelGlobal, home))
+
+	// do not find repository tests run in, nor any other above temp dir
+	defer os.Setenv("GIT_CEILING_DIRECTORIES", os.Getenv("GIT_CEILING_DIRECTORIES"))
+	os.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(home))
+	defer useRepoConfig(t, home)()
+
+	if _, err := OpenRepo(); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected no repository to be found in `%s`", home))
+	}
+
+	result, err := ConfigString("string.outside")
+	testutil.CheckFatal(t, err)
+	if result != "from-global" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `from-global` but got `%s`", result))
+	}
+
+	if _, err := ConfigString("string.missing"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected missing config to be reported"))
+	}
+	values, err := ConfigValues(`^string\.`)
+	testutil.CheckFatal(t, err)
+	if len(values) != 1 || values[0].Origin != "global" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected global `string.outside` only but got %+v", values))
+	}
+}
+
+func TestConfigInclude(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+
+	included := filepath.Join(repo.Workdir(), "included.config")
+	err := ioutil.WriteFile(included, []byte("[string]\n\tincluded = from-include\n"), 0644)
+	testutil.CheckFatal(t, err)
+
+	repoConfig, err := repo.Config()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repoConfig.SetString("include.path", included))
+
+	defer useRepoConfig(t, repo.Workdir())()
+
+	result, err := ConfigString("string.included")
+	testutil.CheckFatal(t, err)
+	if result != "from-include" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `from-include` but got `%s`", result))
+	}
+}
+
+// useRepoConfig makes config be read from repository in dir instead of the one tests run in,
+// returning func that restores it
+func useRepoConfig(t *testing.T, dir string) func() {
+	cwd, err := os.Getwd()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, os.Chdir(dir))
+
+	savedConfig, savedShared, savedRepoPath := config, shared, repoPath
+	config, shared, repoPath = nil, nil, ""
+
+	return func() {
+		os.Chdir(cwd)
+		config, shared, repoPath = savedConfig, savedShared, savedRepoPath
+	}
+}
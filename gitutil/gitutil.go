@@ -3,7 +3,10 @@ package gitutil
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -18,23 +21,6 @@ var (
 	remotes  map[string]*git.Remote
 )
 
-// CredentialsCallback creates ssh key for github creds
-func CredentialsCallback(url string, username string, allowedTypes git.CredType) (git.ErrorCode, *git.Cred) {
-
-	// Get path to public/private keys from config
-	publickey, _ := ConfigString("story.ssh.publickey")
-	privatekey, _ := ConfigString("story.ssh.privatekey")
-
-	ret, cred := git.NewCredSshKey("git", publickey, privatekey, "")
-
-	return git.ErrorCode(ret), &cred
-}
-
-// CertificateCheckCallback for placeholder
-func CertificateCheckCallback(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
-	return 0
-}
-
 // DeleteBranch deletes branch
 func DeleteBranch(repo *git.Repository, remote *git.Remote, branch *git.Branch) error {
 
@@ -75,20 +61,49 @@ func DeleteBranches(repo *git.Repository, remote *git.Remote, branches []*git.Br
 
 // Fetch fetches all delta from remote repo
 func Fetch(repo *git.Repository, remoteName string) error {
+	return fetchRemote(repo, remoteName, fetchParams{
+		prune:        fetchPruneConfig(),
+		showProgress: true,
+	})
+}
 
-	fetchOptions := &git.FetchOptions{
-		RemoteCallbacks: git.RemoteCallbacks{
-			CredentialsCallback:      CredentialsCallback,
-			CertificateCheckCallback: CertificateCheckCallback,
-		},
-	}
+// fetchParams controls what a single fetch downloads and cleans up
+type fetchParams struct {
+	// refspecs to fetch, remote's configured refspecs when empty
+	refspecs []string
+	// prune removes remote-tracking branches deleted on remote
+	prune bool
+	// showProgress renders transfer progress
+	showProgress bool
+	// deadline gives up on the fetch once passed, if set
+	deadline time.Time
+}
+
+// fetchRemote fetches remote with given params
+func fetchRemote(repo *git.Repository, remoteName string, params fetchParams) error {
 
 	remote, err := repo.Remotes.Lookup(remoteName)
 	if err != nil {
 		return fmt.Errorf("Unable to lookup remote: `%s`\n%+v\n", remoteName, err)
 	}
 
-	err = remote.Fetch([]string{}, fetchOptions, "")
+	session := newRemoteSession(remote)
+	session.progress.enabled = session.progress.enabled && params.showProgress
+	session.deadline = params.deadline
+	fetchOptions := &git.FetchOptions{
+		RemoteCallbacks: session.callbacks(),
+		DownloadTags:    fetchTagsConfig(),
+	}
+	if params.prune {
+		fetchOptions.Prune = git.FetchPruneOn
+	}
+
+	refspecs := params.refspecs
+	if refspecs == nil {
+		refspecs = []string{}
+	}
+
+	err = session.done(remote.Fetch(refspecs, fetchOptions, ""))
 	if err != nil {
 		return fmt.Errorf("Unable to fetch for remote: `%s`\n%+v\n", remoteName, err)
 	}
@@ -178,20 +193,48 @@ func GetRepo(repoName string) (*git.Repository, error) {
 	return repo, nil
 }
 
+// OpenRepo opens repository containing working directory, looking in parent directories
+// like git does. `GIT_DIR` and `GIT_CEILING_DIRECTORIES` are honored.
+func OpenRepo() (*git.Repository, error) {
+
+	if dir := os.Getenv("GIT_DIR"); dir != "" {
+		return GetRepo(dir)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find working directory\n%+v", err)
+	}
+
+	var ceilingDirs []string
+	if dirs := os.Getenv("GIT_CEILING_DIRECTORIES"); dirs != "" {
+		ceilingDirs = filepath.SplitList(dirs)
+	}
+
+	path, err := git.Discover(cwd, false, ceilingDirs)
+	if err != nil {
+		return nil, fmt.Errorf("Not a git repository (or any of the parent directories): `%s`", cwd)
+	}
+
+	return GetRepo(path)
+}
+
 // Push pushes given ref to remote repo
 func Push(repo *git.Repository, remote *git.Remote, ref string) error {
 
 	// execute push
-	err := remote.Push([]string{ref}, &git.PushOptions{
-		RemoteCallbacks: git.RemoteCallbacks{
-			CredentialsCallback:      CredentialsCallback,
-			CertificateCheckCallback: CertificateCheckCallback,
-		},
-	})
+	session := newRemoteSession(remote)
+	err := session.done(remote.Push([]string{ref}, &git.PushOptions{
+		RemoteCallbacks: session.callbacks(),
+	}))
 	if err != nil {
 		return fmt.Errorf("Unable to push `%s` to remote `%s`\n%+v\n", ref, remote.Name(), err)
 	}
 
+	if rejected := session.rejected(); len(rejected) > 0 {
+		return &PushRejectedError{Remote: remote.Name(), Rejected: rejected}
+	}
+
 	return nil
 }
 
@@ -282,6 +325,39 @@ func Stash(repo *git.Repository) error {
 	return nil
 }
 
+// ExcludePath adds given path in work tree to `.git/info/exclude` unless it is ignored already,
+// so that files gitcli writes for itself are left out of status and stashes
+func ExcludePath(repo *git.Repository, path string) error {
+
+	rel, err := filepath.Rel(repo.Workdir(), path)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+
+	ignored, err := repo.IsPathIgnored(rel)
+	if err != nil {
+		return err
+	}
+	if ignored {
+		return nil
+	}
+
+	excludePath := filepath.Join(repo.Path(), "info", "exclude")
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(excludePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to exclude `%s`\n%+v", rel, err)
+	}
+	defer f.Close()
+
+	// existing file may not end with newline
+	_, err = fmt.Fprintf(f, "\n/%s\n", rel)
+	return err
+}
+
 // PopLastStash pops stash for current branch
 func PopLastStash(repo *git.Repository) error {
 
@@ -313,6 +389,10 @@ func PopLastStash(repo *git.Repository) error {
 		fmt.Printf("\tPop: Last stash found with index: %d, Oid: %s. Popping...\n", stashIndex, stashCommit)
 		opts, _ := git.DefaultStashApplyOptions()
 		err = repo.Stashes.Pop(stashIndex, opts)
+		if git.IsErrorCode(err, git.ErrConflict) || git.IsErrorCode(err, git.ErrMergeConflict) {
+			// stash stays in the list, so keep pointing at it
+			return &StashConflictError{Index: stashIndex, ID: stashCommit, Err: err}
+		}
 		if err != nil {
 			return err
 		}
@@ -326,6 +406,22 @@ func PopLastStash(repo *git.Repository) error {
 	return nil
 }
 
+// StashConflictError is returned when last stash could not be popped
+// because it conflicts with changes in working tree
+type StashConflictError struct {
+	Index int
+	ID    string
+	Err   error
+}
+
+func (e *StashConflictError) Error() string {
+	return fmt.Sprintf(
+		"Stashed changes conflict with the working tree and were not restored.\n"+
+			"They are kept in stash@{%d} (%s). Run 'git stash pop' once the conflicting files are sorted out.\n%+v",
+		e.Index, e.ID, e.Err,
+	)
+}
+
 func gitUser() (string, string, error) {
 	name, err := ConfigString("user.name")
 	if err != nil {
@@ -386,7 +482,7 @@ func CreateBranch(repo *git.Repository, branchName string, source string) (*git.
 	newBranch, err = repo.LookupBranch(branchName, git.BranchLocal)
 	if err != nil {
 		// find source branch to create new branch from
-		sourceBranch, err := repo.LookupBranch(source, git.BranchRemote)
+		sourceBranch, err := lookupSourceRef(repo, source)
 		if err != nil {
 			return nil, err
 		}
@@ -491,10 +587,13 @@ func FindStashes(repo *git.Repository, pattern string) map[int]*StashInfo {
 	return stashes
 }
 
-// Pull rocks
+// ErrConflicts is returned when merging or rebasing stops on conflicts
+var ErrConflicts = errors.New("Conflicts encountered. Please resolve them.")
+
+// Pull merges given remote or local branch into current branch
 func Pull(repo *git.Repository, name string) error {
 
-	remoteBranch, err := repo.References.Lookup(fmt.Sprintf("refs/remotes/%s", name))
+	remoteBranch, err := lookupSourceRef(repo, name)
 	if err != nil {
 		return err
 	}
@@ -516,6 +615,12 @@ func Pull(repo *git.Repository, name string) error {
 	if analysis&git.MergeAnalysisUpToDate != 0 {
 		fmt.Println("Already up to date.")
 		return nil
+	} else if analysis&git.MergeAnalysisFastForward != 0 {
+
+		fmt.Println("Fast-forwarding")
+
+		return fastForward(repo, remoteBranch.Target())
+
 	} else if analysis&git.MergeAnalysisNormal != 0 {
 
 		fmt.Println("Merging normal")
@@ -532,57 +637,440 @@ func Pull(repo *git.Repository, name string) error {
 		}
 
 		if index.HasConflicts() {
-			return errors.New("Conflicts encountered. Please resolve them.")
+			// remember what is being merged so that resolve can finish the merge
+			branchName, err := CurrentBranchName(repo)
+			if err == nil {
+				err = SetConfigString(fmt.Sprintf("branch.%s.pullsource", branchName), name)
+			}
+			if err != nil {
+				// merge commit falls back to commit id instead of source name
+				log.Println(err)
+			}
+			return ErrConflicts
 		}
 
-		sig, err := repo.DefaultSignature()
+		err = createMergeCommit(repo, index, remoteBranch.Target(), name)
 		if err != nil {
 			return err
 		}
 
-		head, err := repo.Head()
-		if err != nil {
-			return err
-		}
+	} else {
+		return fmt.Errorf("Unexpected merge analysis result %d", analysis)
+	}
 
-		// Get Write Tree
-		treeID, err := index.WriteTree()
-		if err != nil {
-			return err
-		}
+	return nil
+}
 
-		// Get tree to commit with
-		tree, err := repo.LookupTree(treeID)
-		if err != nil {
-			return err
-		}
+// CommitMerge creates the merge commit for merge stopped by conflicts
+// once all of them are resolved
+func CommitMerge(repo *git.Repository) error {
+
+	if repo.State() != git.RepositoryStateMerge {
+		return errors.New("No merge in progress")
+	}
+
+	index, err := repo.Index()
+	if err != nil {
+		return err
+	}
+
+	if index.HasConflicts() {
+		return ErrConflicts
+	}
+
+	// find the commit being merged
+	mergeHead, err := ioutil.ReadFile(filepath.Join(repo.Path(), "MERGE_HEAD"))
+	if err != nil {
+		return fmt.Errorf("Unable to read MERGE_HEAD\n%+v", err)
+	}
+	lines := strings.SplitN(string(mergeHead), "\n", 2)
+	remoteID, err := git.NewOid(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return err
+	}
+
+	branchName, err := CurrentBranchName(repo)
+	if err != nil {
+		return err
+	}
+
+	sourceConfigPath := fmt.Sprintf("branch.%s.pullsource", branchName)
+	source, err := ConfigString(sourceConfigPath)
+	if err != nil || source == "" {
+		source = remoteID.String()
+	}
+
+	err = createMergeCommit(repo, index, remoteID, source)
+	if err != nil {
+		return err
+	}
+
+	// clear out pull source info
+	if err := DeleteConfig(sourceConfigPath); err != nil {
+		return fmt.Errorf("Merge commit is created, but unable to clear `%s`\n%+v", sourceConfigPath, err)
+	}
+
+	return nil
+}
+
+// createMergeCommit commits merged index on top of HEAD and given remote commit
+func createMergeCommit(repo *git.Repository, index *git.Index, remoteID *git.Oid, remoteBranchName string) error {
+
+	sig, err := repo.DefaultSignature()
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+
+	// Get Write Tree
+	treeID, err := index.WriteTree()
+	if err != nil {
+		return err
+	}
+
+	// Get tree to commit with
+	tree, err := repo.LookupTree(treeID)
+	if err != nil {
+		return err
+	}
+
+	// Get local commit to merge to
+	localCommit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return err
+	}
+
+	// Get remote commit to merge with
+	remoteCommit, err := repo.LookupCommit(remoteID)
+	if err != nil {
+		return err
+	}
+
+	// Make the merge commit
+	localBranchName := strings.Replace(head.Name(), "refs/heads/", "", 1)
+	msg := fmt.Sprintf("Merge branch '%s' into '%s'", remoteBranchName, localBranchName)
+	_, err = repo.CreateCommit("HEAD", sig, sig, msg, tree, localCommit, remoteCommit)
+	if err != nil {
+		return err
+	}
+
+	// Clean up repo state
+	repo.StateCleanup()
+
+	return nil
+}
+
+// lookupSourceRef finds reference of given source, looking at
+// remote branches first and then local branches
+func lookupSourceRef(repo *git.Repository, name string) (*git.Reference, error) {
+
+	ref, err := repo.References.Lookup("refs/remotes/" + name)
+	if err == nil {
+		return ref, nil
+	}
+
+	ref, err = repo.References.Lookup("refs/heads/" + name)
+	if err == nil {
+		return ref, nil
+	}
+
+	return nil, fmt.Errorf("Unable to find remote or local branch `%s`\n%+v", name, err)
+}
+
+// IsLocalBranch tells whether given name is a local branch in repo
+func IsLocalBranch(repo *git.Repository, name string) bool {
+	_, err := repo.References.Lookup("refs/heads/" + name)
+	return err == nil
+}
+
+// fastForward moves current branch to given commit and updates working tree
+func fastForward(repo *git.Repository, target *git.Oid) error {
+
+	commit, err := repo.LookupCommit(target)
+	if err != nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	// safe checkout refuses to overwrite local modifications
+	opts := &git.CheckoutOpts{
+		Strategy: git.CheckoutSafe,
+	}
+	if err := repo.CheckoutTree(tree, opts); err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+
+	_, err = head.SetTarget(target, fmt.Sprintf("pull: Fast-forward to %s", target.String()))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Rebase replays commits of current branch on top of given remote or local branch
+func Rebase(repo *git.Repository, name string) error {
+
+	remoteBranch, err := lookupSourceRef(repo, name)
+	if err != nil {
+		return err
+	}
+
+	upstream, err := repo.AnnotatedCommitFromRef(remoteBranch)
+	if err != nil {
+		return err
+	}
 
-		// Get local commit to merge to
-		localCommit, err := repo.LookupCommit(head.Target())
+	// nothing to replay if we are up to date or behind only
+	analysis, _, err := repo.MergeAnalysis([]*git.AnnotatedCommit{upstream})
+	if err != nil {
+		return err
+	}
+	if analysis&git.MergeAnalysisUpToDate != 0 {
+		fmt.Println("Already up to date.")
+		return nil
+	} else if analysis&git.MergeAnalysisFastForward != 0 {
+		fmt.Println("Fast-forwarding")
+		return fastForward(repo, remoteBranch.Target())
+	}
+
+	opts, err := git.DefaultRebaseOptions()
+	if err != nil {
+		return err
+	}
+
+	// rebase HEAD onto upstream
+	rebase, err := repo.InitRebase(nil, upstream, nil, &opts)
+	if err != nil {
+		return fmt.Errorf("Unable to start rebase onto `%s`\n%+v", name, err)
+	}
+	defer rebase.Free()
+
+	return runRebase(repo, rebase)
+}
+
+// RebaseOnto replays commits of current branch made since `since` on top of given branch,
+// like `git rebase --onto <name> <since>`. This moves a story along with its parent story
+// after the parent was rebased, leaving out commits the parent had before.
+func RebaseOnto(repo *git.Repository, name string, since *git.Oid) error {
+
+	ontoRef, err := lookupSourceRef(repo, name)
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+
+	// already on top of given branch, like when resuming stopped story sync
+	based, err := isBasedOn(repo, head.Target(), ontoRef.Target())
+	if err != nil {
+		return err
+	}
+	if based {
+		fmt.Println("Already up to date.")
+		return nil
+	}
+
+	// parent did not move, so its commits are still the ones in current branch
+	if ontoRef.Target().Equal(since) {
+		return Rebase(repo, name)
+	}
+
+	if head.Target().Equal(since) {
+		fmt.Println("Fast-forwarding")
+		return fastForward(repo, ontoRef.Target())
+	}
+
+	onto, err := repo.AnnotatedCommitFromRef(ontoRef)
+	if err != nil {
+		return err
+	}
+	upstream, err := repo.LookupAnnotatedCommit(since)
+	if err != nil {
+		return err
+	}
+
+	opts, err := git.DefaultRebaseOptions()
+	if err != nil {
+		return err
+	}
+
+	rebase, err := repo.InitRebase(nil, upstream, onto, &opts)
+	if err != nil {
+		return fmt.Errorf("Unable to start rebase onto `%s`\n%+v", name, err)
+	}
+	defer rebase.Free()
+
+	return runRebase(repo, rebase)
+}
+
+// IsBranchBasedOn tells whether given local branch already has every commit of branch `name`
+func IsBranchBasedOn(repo *git.Repository, branchName string, name string) (bool, error) {
+
+	ref, err := repo.References.Lookup("refs/heads/" + branchName)
+	if err != nil {
+		return false, err
+	}
+	ontoRef, err := lookupSourceRef(repo, name)
+	if err != nil {
+		return false, err
+	}
+	return isBasedOn(repo, ref.Target(), ontoRef.Target())
+}
+
+// isBasedOn tells whether commit is or descends from onto
+func isBasedOn(repo *git.Repository, commit *git.Oid, onto *git.Oid) (bool, error) {
+	if commit.Equal(onto) {
+		return true, nil
+	}
+	return repo.DescendantOf(commit, onto)
+}
+
+// RebaseContinue commits resolved changes of stopped rebase operation
+// and continues replaying the remaining commits
+func RebaseContinue(repo *git.Repository) error {
+
+	rebase, err := openRebase(repo)
+	if err != nil {
+		return err
+	}
+	defer rebase.Free()
+
+	op := rebase.OperationAt(rebase.CurrentOperationIndex())
+	if op == nil {
+		return errors.New("Unable to find the commit the rebase stopped at")
+	}
+
+	if err := commitRebaseOperation(repo, rebase, op); err != nil {
+		return err
+	}
+
+	return runRebase(repo, rebase)
+}
+
+// RebaseAbort aborts rebase in progress and restores the original branch
+func RebaseAbort(repo *git.Repository) error {
+
+	rebase, err := openRebase(repo)
+	if err != nil {
+		return err
+	}
+	defer rebase.Free()
+
+	fmt.Println("\tRebase: Aborting")
+	if err := rebase.Abort(); err != nil {
+		return fmt.Errorf("Unable to abort rebase\n%+v", err)
+	}
+
+	return nil
+}
+
+// IsRebasing tells whether repo is in the middle of a rebase
+func IsRebasing(repo *git.Repository) bool {
+	return repo.State() == git.RepositoryStateRebaseMerge
+}
+
+func openRebase(repo *git.Repository) (*git.Rebase, error) {
+
+	if !IsRebasing(repo) {
+		return nil, errors.New("No rebase in progress")
+	}
+
+	opts, err := git.DefaultRebaseOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	rebase, err := repo.OpenRebase(&opts)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open rebase in progress\n%+v", err)
+	}
+
+	return rebase, nil
+}
+
+// runRebase applies remaining rebase operations one by one,
+// stopping when an operation leaves conflicts behind
+func runRebase(repo *git.Repository, rebase *git.Rebase) error {
+
+	total := rebase.OperationCount()
+
+	for {
+		op, err := rebase.Next()
+		if git.IsErrorCode(err, git.ErrIterOver) {
+			break
+		}
 		if err != nil {
 			return err
 		}
 
-		// Get remote commit to merge with
-		remoteCommit, err := repo.LookupCommit(remoteBranch.Target())
+		commit, err := repo.LookupCommit(op.Id)
 		if err != nil {
 			return err
 		}
+		fmt.Printf("\tRebase: Applying [%d/%d] %s %s\n",
+			rebase.CurrentOperationIndex()+1, total, op.Id.String()[:7], commit.Summary())
 
-		// Make the merge commit
-		remoteBranchName := strings.Replace(remoteBranch.Name(), "refs/remotes/", "", 1)
-		localBranchName := strings.Replace(head.Name(), "refs/heads/", "", 1)
-		msg := fmt.Sprintf("Merge branch '%s' into '%s'", remoteBranchName, localBranchName)
-		_, err = repo.CreateCommit("HEAD", sig, sig, msg, tree, localCommit, remoteCommit)
-		if err != nil {
+		if err := commitRebaseOperation(repo, rebase, op); err != nil {
 			return err
 		}
+	}
 
-		// Clean up repo state
-		repo.StateCleanup()
+	fmt.Println("\tRebase: Finishing")
+	if err := rebase.Finish(); err != nil {
+		return fmt.Errorf("Unable to finish rebase\n%+v", err)
+	}
 
-	} else {
-		return fmt.Errorf("Unexpected merge analysis result %d", analysis)
+	return nil
+}
+
+// commitRebaseOperation commits currently applied operation
+// keeping original author and message
+func commitRebaseOperation(repo *git.Repository, rebase *git.Rebase, op *git.RebaseOperation) error {
+
+	index, err := repo.Index()
+	if err != nil {
+		return err
+	}
+
+	if index.HasConflicts() {
+		return ErrConflicts
+	}
+
+	original, err := repo.LookupCommit(op.Id)
+	if err != nil {
+		return err
+	}
+
+	committer, err := repo.DefaultSignature()
+	if err != nil {
+		return err
+	}
+
+	err = rebase.Commit(new(git.Oid), original.Author(), committer, original.Message())
+	if git.IsErrorCode(err, git.ErrApplied) {
+		// upstream already contains the same change
+		fmt.Printf("\tRebase: Skipping %s, already applied\n", op.Id.String()[:7])
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to commit rebased `%s`\n%+v", op.Id.String(), err)
 	}
 
 	return nil
@@ -624,6 +1112,114 @@ func SetMostRecentBranch(branchName string) error {
 	return nil
 }
 
+// GetStoryParent fetches the branch given story was created from
+func GetStoryParent(branchName string) (string, error) {
+	parent, err := ConfigString(fmt.Sprintf("branch.%s.storyparent", branchName))
+	if err != nil {
+		return "", err
+	}
+	return parent, nil
+}
+
+// SetStoryParent stores the branch given story was created from
+func SetStoryParent(branchName string, parent string) error {
+	err := SetConfigString(fmt.Sprintf("branch.%s.storyparent", branchName), parent)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetStorySyncTip fetches commit given story was at before story sync in progress
+func GetStorySyncTip(branchName string) (string, error) {
+	tip, err := ConfigString(fmt.Sprintf("branch.%s.storysynctip", branchName))
+	if err != nil {
+		return "", err
+	}
+	return tip, nil
+}
+
+// SetStorySyncTip stores commit given story was at before story sync,
+// so that sync stopped by conflicts moves its children from there when resumed
+func SetStorySyncTip(branchName string, tip string) error {
+	err := SetConfigString(fmt.Sprintf("branch.%s.storysynctip", branchName), tip)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteStorySyncTip forgets commit stored with SetStorySyncTip once story sync is done
+func DeleteStorySyncTip(branchName string) error {
+	return DeleteConfig(fmt.Sprintf("branch.%s.storysynctip", branchName))
+}
+
+// GetStorySyncTop fetches the story stack sync in progress was started from
+func GetStorySyncTop() (string, error) {
+	top, err := ConfigString("story.sync.top")
+	if err != nil {
+		return "", err
+	}
+	return top, nil
+}
+
+// SetStorySyncTop stores the story stack sync was started from, so that sync
+// stopped by conflicts on a story below it gets back to it when resumed
+func SetStorySyncTop(branchName string) error {
+	err := SetConfigString("story.sync.top", branchName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteStorySyncTop forgets story stored with SetStorySyncTop once story sync is done
+func DeleteStorySyncTop() error {
+	return DeleteConfig("story.sync.top")
+}
+
+// GetStoryIssue fetches id of the issue given story works on
+func GetStoryIssue(branchName string) (string, error) {
+	issue, err := ConfigString(fmt.Sprintf("branch.%s.storyissue", branchName))
+	if err != nil {
+		return "", err
+	}
+	return issue, nil
+}
+
+// SetStoryIssue stores id of the issue given story works on
+func SetStoryIssue(branchName string, issue string) error {
+	err := SetConfigString(fmt.Sprintf("branch.%s.storyissue", branchName), issue)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetStoryDbs fetches databases created for given story
+func GetStoryDbs(branchName string) ([]string, error) {
+	dbs, err := ConfigString(fmt.Sprintf("branch.%s.storydbs", branchName))
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, db := range strings.Split(dbs, ",") {
+		if db = strings.TrimSpace(db); db != "" {
+			result = append(result, db)
+		}
+	}
+	return result, nil
+}
+
+// SetStoryDbs stores databases created for given story
+func SetStoryDbs(branchName string, dbs []string) error {
+	err := SetConfigString(fmt.Sprintf("branch.%s.storydbs", branchName), strings.Join(dbs, ","))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // Branches a list of git branches
 type Branches []*git.Branch
 
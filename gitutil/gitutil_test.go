@@ -286,6 +286,269 @@ func TestDeleteBranch(t *testing.T) {
 	}
 }
 
+func TestPullFastForward(t *testing.T) {
+
+	repo := createTestRepo(t)
+	defer cleanupTestRepo(t, repo)
+
+	head, treeID := seedTestRepo(t, repo)
+	parent, err := repo.LookupCommit(head)
+	testutil.CheckFatal(t, err)
+	tree, err := repo.LookupTree(treeID)
+	testutil.CheckFatal(t, err)
+
+	// Create remote branch one commit ahead of local
+	sig := &git.Signature{
+		Name:  "Rand Om Hacker",
+		Email: "random@hacker.com",
+		When:  time.Now(),
+	}
+	remoteID, err := repo.CreateCommit("refs/remotes/test_pull/master", sig, sig, "Remote commit\n", tree, parent)
+	testutil.CheckFatal(t, err)
+
+	err = Pull(repo, "test_pull/master")
+	testutil.CheckFatal(t, err)
+
+	ref, err := repo.Head()
+	testutil.CheckFatal(t, err)
+	if !ref.Target().Equal(remoteID) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected HEAD at %s but got %s", remoteID, ref.Target()))
+	}
+}
+
+func TestRebaseOnto(t *testing.T) {
+
+	repo := createTestRepo(t)
+	defer cleanupTestRepo(t, repo)
+
+	base, _ := seedTestRepo(t, repo)
+
+	// parent story is rewritten after child story was created from it
+	oldParent := commitTestFile(t, repo, "parent.txt", "old\n", base)
+	newParent := commitTestFile(t, repo, "parent.txt", "new\n", base)
+	child := commitTestFile(t, repo, "child.txt", "child\n", oldParent)
+	// leave working tree as child story has it
+	err := ioutil.WriteFile(pathInRepo(repo, "parent.txt"), []byte("old\n"), 0644)
+	testutil.CheckFatal(t, err)
+
+	_, err = repo.References.Create("refs/heads/parent", newParent, true, "")
+	testutil.CheckFatal(t, err)
+	_, err = repo.References.Create("refs/heads/child", child, true, "")
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repo.SetHead("refs/heads/child"))
+
+	err = RebaseOnto(repo, "parent", oldParent)
+	testutil.CheckFatal(t, err)
+
+	ref, err := repo.Head()
+	testutil.CheckFatal(t, err)
+	commit, err := repo.LookupCommit(ref.Target())
+	testutil.CheckFatal(t, err)
+	if commit.ParentCount() != 1 || !commit.Parent(0).Id().Equal(newParent) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected child story to be on top of %s", newParent))
+	}
+
+	// resumed sync leaves story already on top of its parent alone
+	err = RebaseOnto(repo, "parent", oldParent)
+	testutil.CheckFatal(t, err)
+	resumed, err := repo.Head()
+	testutil.CheckFatal(t, err)
+	if !resumed.Target().Equal(ref.Target()) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected child story to stay at %s but got %s", ref.Target(), resumed.Target()))
+	}
+}
+
+func TestStoryParent(t *testing.T) {
+
+	repo := createTestRepo(t)
+	defer cleanupTestRepo(t, repo)
+	defer useRepoConfig(t, repo.Workdir())()
+
+	head, _ := seedTestRepo(t, repo)
+	_, err := repo.References.Create("refs/heads/feature-a", head, true, "")
+	testutil.CheckFatal(t, err)
+	_, err = repo.References.Create("refs/remotes/origin/develop", head, true, "")
+	testutil.CheckFatal(t, err)
+
+	if !IsLocalBranch(repo, "feature-a") {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `feature-a` to be a local branch"))
+	}
+	for _, name := range []string{"origin/develop", "develop", "missing"} {
+		if IsLocalBranch(repo, name) {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` not to be a local branch", name))
+		}
+	}
+
+	// story stacked on local story, which was created from remote branch
+	testutil.CheckFatal(t, SetStoryParent("feature-a", "origin/develop"))
+	testutil.CheckFatal(t, SetStoryParent("feature-b", "feature-a"))
+
+	for story, expected := range map[string]string{"feature-a": "origin/develop", "feature-b": "feature-a"} {
+		parent, err := GetStoryParent(story)
+		testutil.CheckFatal(t, err)
+		if parent != expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected parent `%s` of `%s` but got `%s`", expected, story, parent))
+		}
+	}
+	if _, err := GetStoryParent("feature-c"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected story without recorded parent to be reported"))
+	}
+}
+
+func TestRebase(t *testing.T) {
+
+	repo := createTestRepo(t)
+	defer cleanupTestRepo(t, repo)
+
+	_, theirs := prepareRebaseRepo(t, repo, "ours.txt", "theirs.txt")
+
+	err := Rebase(repo, "upstream")
+	testutil.CheckFatal(t, err)
+
+	checkRebasedOnto(t, repo, theirs)
+	for name, content := range map[string]string{"ours.txt": "ours\n", "theirs.txt": "theirs\n"} {
+		b, err := ioutil.ReadFile(pathInRepo(repo, name))
+		testutil.CheckFatal(t, err)
+		if string(b) != content {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` in %s but got `%s`", content, name, b))
+		}
+	}
+}
+
+func TestRebaseConflictContinue(t *testing.T) {
+
+	repo := createTestRepo(t)
+	defer cleanupTestRepo(t, repo)
+
+	_, theirs := prepareRebaseRepo(t, repo, "a.txt", "a.txt")
+
+	if err := Rebase(repo, "upstream"); err != ErrConflicts {
+		testutil.CheckFatal(t, fmt.Errorf("Expected rebase to stop on conflicts but got %v", err))
+	}
+	if !IsRebasing(repo) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected rebase to be in progress"))
+	}
+
+	// continuing before conflicts are resolved stops again
+	if err := RebaseContinue(repo); err != ErrConflicts {
+		testutil.CheckFatal(t, fmt.Errorf("Expected conflicts to be reported again but got %v", err))
+	}
+
+	err := ioutil.WriteFile(pathInRepo(repo, "a.txt"), []byte("both\n"), 0644)
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, MarkResolved(repo, "a.txt"))
+
+	err = RebaseContinue(repo)
+	testutil.CheckFatal(t, err)
+
+	if IsRebasing(repo) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected rebase to be finished"))
+	}
+	checkRebasedOnto(t, repo, theirs)
+
+	b, err := ioutil.ReadFile(pathInRepo(repo, "a.txt"))
+	testutil.CheckFatal(t, err)
+	if string(b) != "both\n" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected resolved a.txt but got `%s`", b))
+	}
+}
+
+func TestRebaseAbort(t *testing.T) {
+
+	repo := createTestRepo(t)
+	defer cleanupTestRepo(t, repo)
+
+	ours, _ := prepareRebaseRepo(t, repo, "a.txt", "a.txt")
+
+	if err := Rebase(repo, "upstream"); err != ErrConflicts {
+		testutil.CheckFatal(t, fmt.Errorf("Expected rebase to stop on conflicts but got %v", err))
+	}
+
+	err := RebaseAbort(repo)
+	testutil.CheckFatal(t, err)
+
+	if IsRebasing(repo) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected rebase to be aborted"))
+	}
+	head, err := repo.Head()
+	testutil.CheckFatal(t, err)
+	if head.Name() != "refs/heads/master" || !head.Target().Equal(ours) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected HEAD back at master %s but got %s %s", ours, head.Name(), head.Target()))
+	}
+	b, err := ioutil.ReadFile(pathInRepo(repo, "a.txt"))
+	testutil.CheckFatal(t, err)
+	if string(b) != "ours\n" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected original a.txt but got `%s`", b))
+	}
+}
+
+// prepareRebaseRepo checks out master with a commit changing ourFile,
+// next to branch `upstream` with a commit changing theirFile, both on top of seed commit
+func prepareRebaseRepo(t *testing.T, repo *git.Repository, ourFile string, theirFile string) (*git.Oid, *git.Oid) {
+
+	repoConfig, err := repo.Config()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repoConfig.SetString("user.name", "Rand Om Hacker"))
+	testutil.CheckFatal(t, repoConfig.SetString("user.email", "random@hacker.com"))
+
+	base, _ := seedTestRepo(t, repo)
+	ours := commitTestFile(t, repo, ourFile, "ours\n", base)
+	theirs := commitTestFile(t, repo, theirFile, "theirs\n", base)
+
+	_, err = repo.References.Create("refs/heads/master", ours, true, "")
+	testutil.CheckFatal(t, err)
+	_, err = repo.References.Create("refs/heads/upstream", theirs, true, "")
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repo.CheckoutHead(&git.CheckoutOpts{Strategy: git.CheckoutForce | git.CheckoutRemoveUntracked}))
+
+	return ours, theirs
+}
+
+// checkRebasedOnto checks that master has a single commit on top of given commit
+func checkRebasedOnto(t *testing.T, repo *git.Repository, onto *git.Oid) {
+
+	head, err := repo.Head()
+	testutil.CheckFatal(t, err)
+	if head.Name() != "refs/heads/master" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected master to be checked out but got %s", head.Name()))
+	}
+	commit, err := repo.LookupCommit(head.Target())
+	testutil.CheckFatal(t, err)
+	if commit.ParentCount() != 1 || !commit.Parent(0).Id().Equal(onto) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected master to be rebased onto %s", onto))
+	}
+}
+
+// commitTestFile commits file on top of parent, without moving any branch.
+// Index is left with the tree of the new commit.
+func commitTestFile(t *testing.T, repo *git.Repository, name string, content string, parentID *git.Oid) *git.Oid {
+
+	parent, err := repo.LookupCommit(parentID)
+	testutil.CheckFatal(t, err)
+	parentTree, err := parent.Tree()
+	testutil.CheckFatal(t, err)
+
+	err = ioutil.WriteFile(pathInRepo(repo, name), []byte(content), 0644)
+	testutil.CheckFatal(t, err)
+
+	idx, err := repo.Index()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, idx.ReadTree(parentTree))
+	testutil.CheckFatal(t, idx.AddByPath(name))
+	treeID, err := idx.WriteTree()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, idx.Write())
+
+	tree, err := repo.LookupTree(treeID)
+	testutil.CheckFatal(t, err)
+
+	sig := &git.Signature{Name: "Rand Om Hacker", Email: "random@hacker.com", When: time.Now()}
+	commitID, err := repo.CreateCommit("", sig, sig, "Commit "+name+"\n", tree, parent)
+	testutil.CheckFatal(t, err)
+
+	return commitID
+}
+
 func cleanupTestRepo(t *testing.T, r *git.Repository) {
 	var err error
 	if r.IsBare() {
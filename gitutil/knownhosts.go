@@ -0,0 +1,194 @@
+package gitutil
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hostKeyStatus is the result of looking up host key in known_hosts
+type hostKeyStatus int
+
+const (
+	// hostKeyUnknown means the host has no entries
+	hostKeyUnknown hostKeyStatus = iota
+	// hostKeyKnown means one of the entries for the host matches the key
+	hostKeyKnown
+	// hostKeyMismatch means the host has entries but none matches the key
+	hostKeyMismatch
+	// hostKeyRevoked means the key is marked as @revoked
+	hostKeyRevoked
+)
+
+// hostKeyHashes are fingerprints of the host key libgit2 provides
+type hostKeyHashes struct {
+	md5  []byte
+	sha1 []byte
+}
+
+// matches tells whether given public key has the same fingerprints
+func (h hostKeyHashes) matches(key []byte) bool {
+	if h.md5 == nil && h.sha1 == nil {
+		return false
+	}
+	if h.md5 != nil {
+		sum := md5.Sum(key)
+		if !bytes.Equal(sum[:], h.md5) {
+			return false
+		}
+	}
+	if h.sha1 != nil {
+		sum := sha1.Sum(key)
+		if !bytes.Equal(sum[:], h.sha1) {
+			return false
+		}
+	}
+	return true
+}
+
+// knownHostsPath returns known_hosts file to verify ssh host keys with
+func knownHostsPath() string {
+	if path, err := ConfigString("story.ssh.knownhosts"); err == nil && path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts")
+}
+
+// knownHostName formats host the way ssh writes it in known_hosts
+func knownHostName(host string, port string) string {
+	if port == "" || port == "22" {
+		return host
+	}
+	return fmt.Sprintf("[%s]:%s", host, port)
+}
+
+// lookupHostKey checks known_hosts entries read from r for given host and key.
+// It returns the status and line number of the entry that decided it.
+// Like ssh, host with entries none of which matches is a mismatch, line being
+// of the first of them. Key type is not told apart, since libgit2 gives only fingerprints.
+func lookupHostKey(r io.Reader, host string, hashes hostKeyHashes) (hostKeyStatus, int, error) {
+
+	status, line := hostKeyUnknown, 0
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		marker := ""
+		if strings.HasPrefix(fields[0], "@") {
+			marker = fields[0]
+			fields = fields[1:]
+		}
+		if len(fields) < 3 || marker == "@cert-authority" {
+			continue
+		}
+
+		if !matchHostPatterns(fields[0], host) {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			continue
+		}
+
+		if hashes.matches(key) {
+			if marker == "@revoked" {
+				return hostKeyRevoked, lineNum, nil
+			}
+			status, line = hostKeyKnown, lineNum
+		} else if marker == "" && status == hostKeyUnknown {
+			status, line = hostKeyMismatch, lineNum
+		}
+	}
+
+	return status, line, scanner.Err()
+}
+
+// matchHostPatterns matches host against comma separated host patterns of known_hosts entry,
+// supporting hashed hosts, wildcards and negation
+func matchHostPatterns(patterns string, host string) bool {
+
+	// hashed entry: |1|base64(salt)|base64(hmac-sha1(salt, host))
+	if strings.HasPrefix(patterns, "|1|") {
+		parts := strings.Split(patterns[3:], "|")
+		if len(parts) != 2 {
+			return false
+		}
+		salt, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return false
+		}
+		expected, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(host))
+		return hmac.Equal(mac.Sum(nil), expected)
+	}
+
+	matched := false
+	for _, pattern := range strings.Split(patterns, ",") {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if matchHostPattern(pattern, host) {
+			if negate {
+				return false
+			}
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// matchHostPattern matches host against single pattern where only `*` and `?` are special
+func matchHostPattern(pattern string, host string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == host
+	}
+	escaped := strings.NewReplacer("[", "\\[", "]", "\\]", "\\", "\\\\").Replace(pattern)
+	ok, _ := filepath.Match(escaped, host)
+	return ok
+}
+
+// splitHostPort extracts host and port of remote url, in url or scp-like form
+func splitHostPort(remoteURL string) (string, string) {
+
+	rest := remoteURL
+	if i := strings.Index(rest, "://"); i > -1 {
+		rest = rest[i+3:]
+		if j := strings.Index(rest, "/"); j > -1 {
+			rest = rest[:j]
+		}
+	} else if i := strings.Index(rest, ":"); i > -1 {
+		rest = rest[:i]
+	}
+
+	if at := strings.LastIndex(rest, "@"); at > -1 {
+		rest = rest[at+1:]
+	}
+
+	if host, port, err := net.SplitHostPort(rest); err == nil {
+		return host, port
+	}
+
+	return rest, ""
+}
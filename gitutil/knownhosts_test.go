@@ -0,0 +1,137 @@
+package gitutil
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+	git "github.com/libgit2/git2go"
+)
+
+func TestLookupHostKey(t *testing.T) {
+
+	key := []byte("ssh-ed25519 host key")
+	otherKey := []byte("ssh-ed25519 other key")
+	encoded := base64.StdEncoding.EncodeToString(key)
+	otherEncoded := base64.StdEncoding.EncodeToString(otherKey)
+
+	md5Sum := md5.Sum(key)
+	sha1Sum := sha1.Sum(key)
+	hashes := hostKeyHashes{md5: md5Sum[:], sha1: sha1Sum[:]}
+
+	knownHosts := strings.Join([]string{
+		"# comment",
+		"github.com,192.30.252.1 ssh-ed25519 " + encoded,
+		"[git.example.com]:2222 ssh-ed25519 " + encoded,
+		"changed.example.com ssh-ed25519 " + otherEncoded,
+		"rsa.example.com ssh-rsa " + otherEncoded,
+		hashedHost("hashed.example.com") + " ssh-ed25519 " + encoded,
+		"*.wild.example.com,!bad.wild.example.com ssh-ed25519 " + encoded,
+		"@revoked revoked.example.com ssh-ed25519 " + encoded,
+		"revoked.example.com ssh-ed25519 " + encoded,
+	}, "\n")
+
+	cases := []struct {
+		host     string
+		expected hostKeyStatus
+	}{
+		{"github.com", hostKeyKnown},
+		{"192.30.252.1", hostKeyKnown},
+		{"[git.example.com]:2222", hostKeyKnown},
+		{"git.example.com", hostKeyUnknown},
+		{"changed.example.com", hostKeyMismatch},
+		// key type is not known, so key of another type cannot be told from a changed key
+		{"rsa.example.com", hostKeyMismatch},
+		{"hashed.example.com", hostKeyKnown},
+		{"foo.wild.example.com", hostKeyKnown},
+		{"bad.wild.example.com", hostKeyUnknown},
+		{"revoked.example.com", hostKeyRevoked},
+		{"unknown.example.com", hostKeyUnknown},
+	}
+
+	for _, c := range cases {
+		status, _, err := lookupHostKey(strings.NewReader(knownHosts), c.host, hashes)
+		testutil.CheckFatal(t, err)
+		if status != c.expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected status %d for `%s` but got %d", c.expected, c.host, status))
+		}
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+
+	cases := []struct {
+		url  string
+		host string
+		port string
+	}{
+		{"git@github.com:kidonchu/gitcli.git", "github.com", ""},
+		{"ssh://git@git.example.com:2222/repo.git", "git.example.com", "2222"},
+		{"https://github.com/kidonchu/gitcli.git", "github.com", ""},
+	}
+
+	for _, c := range cases {
+		host, port := splitHostPort(c.url)
+		if host != c.host || port != c.port {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` `%s` for `%s` but got `%s` `%s`", c.host, c.port, c.url, host, port))
+		}
+	}
+}
+
+func hashedHost(host string) string {
+	salt := []byte("0123456789abcdefghij")
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyHostKey(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+
+	key := []byte("ssh-ed25519 host key")
+	otherEncoded := base64.StdEncoding.EncodeToString([]byte("ssh-rsa other key"))
+	knownHosts := strings.Join([]string{
+		"github.com ssh-ed25519 " + base64.StdEncoding.EncodeToString(key),
+		"rsa.example.com ssh-rsa " + otherEncoded,
+	}, "\n")
+	path := filepath.Join(repo.Workdir(), "known_hosts")
+	testutil.CheckFatal(t, ioutil.WriteFile(path, []byte(knownHosts), 0644))
+
+	repoConfig, err := repo.Config()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repoConfig.SetString("story.ssh.knownhosts", path))
+	defer useRepoConfig(t, repo.Workdir())()
+
+	// fingerprints are all libgit2 gives
+	cert := &git.Certificate{Kind: git.CertificateHostkey}
+	cert.Hostkey.Kind = git.HostkeyMD5 | git.HostkeySHA1
+	cert.Hostkey.HashMD5 = md5.Sum(key)
+	cert.Hostkey.HashSHA1 = sha1.Sum(key)
+
+	verifier := &hostVerifier{url: "git@github.com:kidonchu/gitcli.git"}
+	testutil.CheckFatal(t, verifier.verifyHostKey(cert, "github.com"))
+
+	// host listed with other keys only does not match, as ssh tells
+	verifier = &hostVerifier{url: "git@rsa.example.com:repo.git"}
+	err = verifier.verifyHostKey(cert, "rsa.example.com")
+	if err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected key not listed for `rsa.example.com` to be refused"))
+	}
+	if !strings.Contains(err.Error(), "does not match "+path+":2") {
+		testutil.CheckFatal(t, fmt.Errorf("Expected mismatch with line 2 to be reported but got: %v", err))
+	}
+
+	verifier = &hostVerifier{url: "git@unknown.example.com:repo.git"}
+	if err := verifier.verifyHostKey(cert, "unknown.example.com"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected unknown host to be refused"))
+	}
+}
@@ -0,0 +1,122 @@
+package gitutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	git "github.com/libgit2/git2go"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// progressInterval limits how often progress line is redrawn
+const progressInterval = 100 * time.Millisecond
+
+// progressRenderer renders transfer progress of remote operations on a single line.
+// Nothing is rendered when output is not a terminal.
+type progressRenderer struct {
+	out      io.Writer
+	enabled  bool
+	drawn    bool
+	lastDraw time.Time
+}
+
+func newProgressRenderer() *progressRenderer {
+	return &progressRenderer{
+		out:     os.Stderr,
+		enabled: terminal.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+// transfer is used as TransferProgressCallback of fetch
+func (p *progressRenderer) transfer(stats git.TransferProgress) git.ErrorCode {
+	done := stats.ReceivedObjects == stats.TotalObjects && stats.IndexedDeltas == stats.TotalDeltas
+	p.draw(formatTransferProgress(stats), done)
+	return git.ErrOk
+}
+
+// pushTransfer is used as PushTransferProgressCallback of push
+func (p *progressRenderer) pushTransfer(current, total uint32, bytes uint) git.ErrorCode {
+	p.draw(formatPushProgress(current, total, bytes), current == total)
+	return git.ErrOk
+}
+
+// sideband is used as SidebandProgressCallback, printing what remote reports
+func (p *progressRenderer) sideband(str string) git.ErrorCode {
+	if !p.enabled {
+		return git.ErrOk
+	}
+	for _, line := range strings.FieldsFunc(str, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		p.clear()
+		fmt.Fprintf(p.out, "remote: %s\n", line)
+	}
+	return git.ErrOk
+}
+
+// finish moves past the progress line
+func (p *progressRenderer) finish() {
+	if p.drawn {
+		fmt.Fprintln(p.out)
+		p.drawn = false
+	}
+}
+
+func (p *progressRenderer) draw(line string, force bool) {
+	if !p.enabled {
+		return
+	}
+	if !force && time.Since(p.lastDraw) < progressInterval {
+		return
+	}
+	p.lastDraw = time.Now()
+	p.drawn = true
+	fmt.Fprintf(p.out, "\r\x1b[K%s", line)
+}
+
+func (p *progressRenderer) clear() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\x1b[K")
+		p.drawn = false
+	}
+}
+
+// formatTransferProgress describes fetch progress the way git does
+func formatTransferProgress(stats git.TransferProgress) string {
+	line := fmt.Sprintf("Receiving objects: %3d%% (%d/%d), %s",
+		percent(stats.ReceivedObjects, stats.TotalObjects),
+		stats.ReceivedObjects, stats.TotalObjects, formatBytes(stats.ReceivedBytes))
+	if stats.TotalDeltas > 0 {
+		line += fmt.Sprintf(" | Resolving deltas: %3d%% (%d/%d)",
+			percent(stats.IndexedDeltas, stats.TotalDeltas), stats.IndexedDeltas, stats.TotalDeltas)
+	}
+	return line
+}
+
+// formatPushProgress describes push progress the way git does
+func formatPushProgress(current, total uint32, bytes uint) string {
+	return fmt.Sprintf("Writing objects: %3d%% (%d/%d), %s",
+		percent(uint(current), uint(total)), current, total, formatBytes(bytes))
+}
+
+func percent(current, total uint) uint {
+	if total == 0 {
+		return 100
+	}
+	return current * 100 / total
+}
+
+// formatBytes formats byte count in human readable units
+func formatBytes(bytes uint) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d bytes", bytes)
+	}
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %ciB", value, "KMGT"[exp])
+}
@@ -0,0 +1,36 @@
+package gitutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+	git "github.com/libgit2/git2go"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[uint]string{
+		512:             "512 bytes",
+		2048:            "2.00 KiB",
+		5 * 1024 * 1024: "5.00 MiB",
+	}
+	for bytes, expected := range cases {
+		if result := formatBytes(bytes); result != expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", expected, result))
+		}
+	}
+}
+
+func TestFormatTransferProgress(t *testing.T) {
+	stats := git.TransferProgress{
+		TotalObjects:    200,
+		ReceivedObjects: 50,
+		TotalDeltas:     10,
+		IndexedDeltas:   5,
+		ReceivedBytes:   4096,
+	}
+	expected := "Receiving objects:  25% (50/200), 4.00 KiB | Resolving deltas:  50% (5/10)"
+	if result := formatTransferProgress(stats); result != expected {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", expected, result))
+	}
+}
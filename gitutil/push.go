@@ -0,0 +1,188 @@
+package gitutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	git "github.com/libgit2/git2go"
+)
+
+// zeroOid is how git hooks describe a ref that does not exist
+const zeroOid = "0000000000000000000000000000000000000000"
+
+// PushOptions controls how PushBranch pushes
+type PushOptions struct {
+	// ForceWithLease overwrites remote branch, but only if it still points
+	// at what remote-tracking branch says, so nobody else's work is lost.
+	// libgit2 cannot make the push itself conditional, so remote is checked
+	// right before pushing, and a push landing in between is still overwritten.
+	ForceWithLease bool
+	// NoVerify skips pre-push hook
+	NoVerify bool
+}
+
+// PushResult reports what remote said about a push
+type PushResult struct {
+	// Statuses maps pushed refs to rejection reason, empty when the ref was updated
+	Statuses map[string]string
+	// Messages are lines remote printed while pushing, like links to open pull request
+	Messages []string
+	// Streamed tells Messages were already shown as remote printed them
+	Streamed bool
+}
+
+// PushRejectedError is returned when remote refuses to update some of pushed refs
+type PushRejectedError struct {
+	Remote   string
+	Rejected map[string]string
+}
+
+func (e *PushRejectedError) Error() string {
+	refs := make([]string, 0, len(e.Rejected))
+	for refname := range e.Rejected {
+		refs = append(refs, refname)
+	}
+	sort.Strings(refs)
+
+	lines := []string{fmt.Sprintf("Remote `%s` rejected the push:", e.Remote)}
+	for _, refname := range refs {
+		lines = append(lines, fmt.Sprintf("  %s: %s", refname, e.Rejected[refname]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// StaleLeaseError is returned when remote branch moved since it was last fetched
+type StaleLeaseError struct {
+	Ref      string
+	Expected string
+	Actual   string
+}
+
+func (e *StaleLeaseError) Error() string {
+	return fmt.Sprintf(
+		"Remote `%s` is at %s but was expected at %s. Someone else has pushed to it.\n"+
+			"Fetch and review their changes before forcing.",
+		e.Ref, shortOid(e.Actual), shortOid(e.Expected),
+	)
+}
+
+// PushBranch pushes local branch to the branch of the same name on remote
+func PushBranch(repo *git.Repository, remote *git.Remote, branchName string, opts PushOptions) (*PushResult, error) {
+
+	ref := "refs/heads/" + branchName
+
+	local, err := repo.References.Lookup(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find local branch `%s`\n%+v", branchName, err)
+	}
+
+	// what we believe remote branch is at, from last fetch
+	expected := zeroOid
+	tracking, err := repo.References.Lookup(fmt.Sprintf("refs/remotes/%s/%s", remote.Name(), branchName))
+	if err == nil {
+		expected = tracking.Target().String()
+	}
+
+	refspec := fmt.Sprintf("%s:%s", ref, ref)
+	if opts.ForceWithLease {
+		actual, err := remoteRefOid(remote, ref)
+		if err != nil {
+			return nil, err
+		}
+		if actual != expected {
+			return nil, &StaleLeaseError{Ref: fmt.Sprintf("%s/%s", remote.Name(), branchName), Expected: expected, Actual: actual}
+		}
+		refspec = "+" + refspec
+	}
+
+	if !opts.NoVerify {
+		update := fmt.Sprintf("%s %s %s %s\n", ref, local.Target().String(), ref, expected)
+		if err := runPrePushHook(repo, remote, update); err != nil {
+			return nil, err
+		}
+	}
+
+	session := newRemoteSession(remote)
+	err = session.done(remote.Push([]string{refspec}, &git.PushOptions{
+		RemoteCallbacks: session.callbacks(),
+	}))
+
+	result := &PushResult{Statuses: session.statuses, Messages: session.messages, Streamed: session.progress.enabled}
+	if err != nil {
+		return result, fmt.Errorf("Unable to push `%s` to remote `%s`\n%+v", ref, remote.Name(), err)
+	}
+	if rejected := session.rejected(); len(rejected) > 0 {
+		return result, &PushRejectedError{Remote: remote.Name(), Rejected: rejected}
+	}
+
+	return result, nil
+}
+
+// remoteRefOid asks remote where given ref currently points at
+func remoteRefOid(remote *git.Remote, ref string) (string, error) {
+
+	session := newRemoteSession(remote)
+	callbacks := session.callbacks()
+	err := session.done(remote.ConnectFetch(&callbacks, &git.ProxyOptions{}, nil))
+	if err != nil {
+		return "", fmt.Errorf("Unable to connect to remote `%s`\n%+v", remote.Name(), err)
+	}
+	defer remote.Disconnect()
+
+	heads, err := remote.Ls(ref)
+	if err != nil {
+		return "", fmt.Errorf("Unable to list `%s` on remote `%s`\n%+v", ref, remote.Name(), err)
+	}
+
+	for _, head := range heads {
+		if head.Name == ref {
+			return head.Id.String(), nil
+		}
+	}
+
+	return zeroOid, nil
+}
+
+// runPrePushHook runs pre-push hook of repo, if any, the way git push does
+func runPrePushHook(repo *git.Repository, remote *git.Remote, updates string) error {
+
+	hooksPath, err := ConfigString("core.hooksPath")
+	if err != nil || hooksPath == "" {
+		hooksPath = filepath.Join(repo.Path(), "hooks")
+	} else if !filepath.IsAbs(hooksPath) {
+		hooksPath = filepath.Join(repo.Workdir(), hooksPath)
+	}
+
+	hook := filepath.Join(hooksPath, "pre-push")
+	info, err := os.Stat(hook)
+	if err != nil || info.Mode()&0111 == 0 {
+		return nil
+	}
+
+	fmt.Println("\tPush: Running pre-push hook")
+	cmd := exec.Command(hook, remote.Name(), remote.Url())
+	cmd.Dir = repo.Workdir()
+	cmd.Stdin = bytes.NewBufferString(updates)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pre-push hook refused the push: %v\nPass --no-verify to skip it", err)
+	}
+
+	return nil
+}
+
+func shortOid(oid string) string {
+	if oid == zeroOid {
+		return "(none)"
+	}
+	if len(oid) > 7 {
+		return oid[:7]
+	}
+	return oid
+}
@@ -0,0 +1,46 @@
+package gitutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestCaptureSideband(t *testing.T) {
+	s := &remoteSession{}
+
+	// lines arrive in pieces, and progress lines are redrawn with carriage returns
+	s.captureSideband("Resolving deltas:  50%\rResolving deltas: 100%\n")
+	s.captureSideband("Create a pull request for 'feature' on GitHub by visiting:\n      https://github.com/foo/bar/pull/new/fea")
+	s.captureSideband("ture\n")
+	s.captureSideband("done")
+	s.captureSideband("\n")
+
+	expected := []string{
+		"Resolving deltas: 100%",
+		"Create a pull request for 'feature' on GitHub by visiting:",
+		"https://github.com/foo/bar/pull/new/feature",
+		"done",
+	}
+	if strings.Join(s.messages, "|") != strings.Join(expected, "|") {
+		testutil.CheckFatal(t, fmt.Errorf("Expected %q but got %q", expected, s.messages))
+	}
+}
+
+func TestPushUpdateReference(t *testing.T) {
+	s := &remoteSession{}
+	s.pushUpdateReference("refs/heads/a", "")
+	s.pushUpdateReference("refs/heads/b", "non-fast-forward")
+
+	rejected := s.rejected()
+	if len(rejected) != 1 || rejected["refs/heads/b"] != "non-fast-forward" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected only `refs/heads/b` to be rejected but got %v", rejected))
+	}
+
+	err := &PushRejectedError{Remote: "origin", Rejected: rejected}
+	if !strings.Contains(err.Error(), "refs/heads/b: non-fast-forward") {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected error message `%s`", err.Error()))
+	}
+}
@@ -0,0 +1,254 @@
+package gitutil
+
+import (
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	git "github.com/libgit2/git2go"
+)
+
+// remoteSession holds state shared by callbacks of a single fetch or push
+type remoteSession struct {
+	url      string
+	creds    *credentials
+	verifier *hostVerifier
+	progress *progressRenderer
+
+	// deadline aborts the operation at the next callback once passed
+	deadline time.Time
+
+	// messages are complete lines remote printed over sideband
+	messages []string
+	sideBuf  string
+	// statuses maps pushed refs to rejection reason reported by remote,
+	// empty reason meaning the ref was updated
+	statuses map[string]string
+}
+
+func newRemoteSession(remote *git.Remote) *remoteSession {
+	return &remoteSession{
+		url:      remote.Url(),
+		creds:    &credentials{},
+		verifier: &hostVerifier{url: remote.Url()},
+		progress: newProgressRenderer(),
+	}
+}
+
+// callbacks returns callbacks to pass to libgit2
+func (s *remoteSession) callbacks() git.RemoteCallbacks {
+	return git.RemoteCallbacks{
+		CredentialsCallback:          s.creds.callback,
+		CertificateCheckCallback:     s.verifier.callback,
+		TransferProgressCallback:     s.transfer,
+		PushTransferProgressCallback: s.pushTransfer,
+		SidebandProgressCallback:     s.sideband,
+		PushUpdateReferenceCallback:  s.pushUpdateReference,
+	}
+}
+
+func (s *remoteSession) pushUpdateReference(refname, status string) git.ErrorCode {
+	if s.statuses == nil {
+		s.statuses = make(map[string]string)
+	}
+	s.statuses[refname] = status
+	return git.ErrOk
+}
+
+// rejected returns refs remote refused to update, with reasons
+func (s *remoteSession) rejected() map[string]string {
+	rejected := make(map[string]string)
+	for refname, status := range s.statuses {
+		if status != "" {
+			rejected[refname] = status
+		}
+	}
+	return rejected
+}
+
+func (s *remoteSession) transfer(stats git.TransferProgress) git.ErrorCode {
+	if s.expired() {
+		return git.ErrUser
+	}
+	return s.progress.transfer(stats)
+}
+
+func (s *remoteSession) pushTransfer(current, total uint32, bytes uint) git.ErrorCode {
+	if s.expired() {
+		return git.ErrUser
+	}
+	return s.progress.pushTransfer(current, total, bytes)
+}
+
+func (s *remoteSession) sideband(str string) git.ErrorCode {
+	if s.expired() {
+		return git.ErrUser
+	}
+	s.captureSideband(str)
+	return s.progress.sideband(str)
+}
+
+// captureSideband collects lines remote prints, which can arrive split across calls.
+// Only the last state of lines redrawn with carriage returns is kept.
+func (s *remoteSession) captureSideband(str string) {
+	s.sideBuf += str
+	for {
+		i := strings.Index(s.sideBuf, "\n")
+		if i < 0 {
+			break
+		}
+		line := s.sideBuf[:i]
+		s.sideBuf = s.sideBuf[i+1:]
+		if j := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); j > -1 {
+			line = line[j+1:]
+		}
+		line = strings.TrimSpace(line)
+		if line != "" {
+			s.messages = append(s.messages, line)
+		}
+	}
+}
+
+func (s *remoteSession) expired() bool {
+	return !s.deadline.IsZero() && time.Now().After(s.deadline)
+}
+
+// done finishes the session with result of the operation,
+// returning the reason of failure if callbacks know better than libgit2
+func (s *remoteSession) done(err error) error {
+
+	s.progress.finish()
+	s.creds.done(err)
+
+	// keep whatever remote printed last without newline
+	s.captureSideband("\n")
+
+	if err != nil && s.verifier.err != nil {
+		return s.verifier.err
+	}
+	if err != nil && s.expired() {
+		return fmt.Errorf("Timed out talking to `%s`", s.url)
+	}
+
+	return err
+}
+
+// hostVerifier verifies identity of the host remote operations connect to
+type hostVerifier struct {
+	url string
+	err error
+}
+
+// CertificateCheckCallback verifies host the way remote operations of gitutil do,
+// for callers setting up their own git.RemoteCallbacks. The reason of failure is logged,
+// and hosts on non-standard ssh port are looked up in known_hosts without it.
+//
+// Deprecated: remote operations of gitutil set up their own callbacks.
+func CertificateCheckCallback(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
+	verifier := &hostVerifier{}
+	ret := verifier.callback(cert, valid, hostname)
+	if verifier.err != nil {
+		log.Println(verifier.err)
+	}
+	return ret
+}
+
+// callback is used as CertificateCheckCallback of remote operations
+func (v *hostVerifier) callback(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
+
+	if insecure, _ := ConfigBool("story.insecure"); insecure {
+		log.Printf("WARNING: Skipping verification of `%s` since story.insecure is set", hostname)
+		return git.ErrOk
+	}
+
+	switch cert.Kind {
+	case git.CertificateX509:
+		v.err = verifyTLSCertificate(cert, valid, hostname)
+	case git.CertificateHostkey:
+		v.err = v.verifyHostKey(cert, hostname)
+	default:
+		v.err = fmt.Errorf("Unknown certificate type presented by `%s`", hostname)
+	}
+
+	if v.err != nil {
+		return git.ErrCertificate
+	}
+
+	return git.ErrOk
+}
+
+// verifyTLSCertificate accepts certificates libgit2 validated
+// or ones that verify against the system pool
+func verifyTLSCertificate(cert *git.Certificate, valid bool, hostname string) error {
+
+	if valid {
+		return nil
+	}
+
+	if cert.X509 == nil {
+		return fmt.Errorf("TLS certificate of `%s` could not be verified", hostname)
+	}
+
+	_, err := cert.X509.Verify(x509.VerifyOptions{DNSName: hostname})
+	if err != nil {
+		return fmt.Errorf(
+			"TLS certificate of `%s` could not be verified: %v\n"+
+				"Set 'git config story.insecure true' only if you trust this host",
+			hostname, err,
+		)
+	}
+
+	return nil
+}
+
+// verifyHostKey checks ssh host key against known_hosts
+func (v *hostVerifier) verifyHostKey(cert *git.Certificate, hostname string) error {
+
+	// libgit2 only gives fingerprints, not the type of the key
+	var hashes hostKeyHashes
+	if cert.Hostkey.Kind&git.HostkeyMD5 != 0 {
+		hashes.md5 = cert.Hostkey.HashMD5[:]
+	}
+	if cert.Hostkey.Kind&git.HostkeySHA1 != 0 {
+		hashes.sha1 = cert.Hostkey.HashSHA1[:]
+	}
+
+	_, port := splitHostPort(v.url)
+	host := knownHostName(hostname, port)
+
+	path := knownHostsPath()
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Unable to verify host key of `%s`, could not read `%s`\n%+v", host, path, err)
+	}
+	defer f.Close()
+
+	status, line, err := lookupHostKey(f, host, hashes)
+	if err != nil {
+		return fmt.Errorf("Unable to read `%s`\n%+v", path, err)
+	}
+
+	switch status {
+	case hostKeyKnown:
+		return nil
+	case hostKeyRevoked:
+		return fmt.Errorf("Host key of `%s` is revoked in %s:%d", host, path, line)
+	case hostKeyMismatch:
+		return fmt.Errorf(
+			"Host key verification failed: key presented by `%s` does not match %s:%d\n"+
+				"Someone could be eavesdropping, the host key has just been changed, "+
+				"or the host presented a key of type not listed for it.\n"+
+				"Connect once with ssh, which tells which",
+			host, path, line,
+		)
+	}
+
+	return fmt.Errorf(
+		"Host key verification failed: `%s` is not in %s\n"+
+			"Connect once with ssh, or run 'ssh-keyscan %s >> %s' after checking its fingerprint",
+		host, path, hostname, path,
+	)
+}
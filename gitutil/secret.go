@@ -0,0 +1,93 @@
+package gitutil
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// ConfigSecret finds secret value such as token or password for given config name.
+// Sources are looked at in the following order, so that secrets do not need
+// to be stored in git config as plain text.
+//
+//  1. environment variable, e.g. GITCLI_OAUTHTOKEN for `story.oauthtoken`
+//  2. output of command in `<name>cmd`, e.g. `story.oauthtokencmd`
+//  3. contents of file in `<name>file`, which must not be accessible by other users
+//  4. plain value of `<name>`
+//
+// Empty string is returned without error if none of them is set.
+func ConfigSecret(name string) (string, error) {
+
+	if value := os.Getenv(SecretEnvName(name)); value != "" {
+		return value, nil
+	}
+
+	if command, err := ConfigString(name + "cmd"); err == nil && command != "" {
+		return secretFromCommand(command)
+	}
+
+	if path, err := ConfigString(name + "file"); err == nil && path != "" {
+		return secretFromFile(path)
+	}
+
+	value, _ := ConfigString(name)
+	return value, nil
+}
+
+// envNameUnsafe matches characters shells do not take in environment variable names
+var envNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// SecretEnvName returns environment variable secret of given config name is read from.
+// Every character other than letters, digits and `_` becomes `_`, so that
+// `story.db.app-ro.pass` is read from GITCLI_DB_APP_RO_PASS.
+func SecretEnvName(name string) string {
+	name = strings.TrimPrefix(name, "story.")
+	return "GITCLI_" + strings.ToUpper(envNameUnsafe.ReplaceAllString(name, "_"))
+}
+
+// secretFromCommand runs command, like `pass show github/token`, and returns its first line
+func secretFromCommand(command string) (string, error) {
+
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Unable to run secret command `%s`\n%+v", command, err)
+	}
+
+	return firstLine(stdout.String()), nil
+}
+
+// secretFromFile reads secret from file only readable by its owner
+func secretFromFile(path string) (string, error) {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read secret file `%s`\n%+v", path, err)
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf(
+			"Secret file `%s` is accessible by other users (%s). Run 'chmod 600 %s' to fix it",
+			path, info.Mode().Perm(), path,
+		)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read secret file `%s`\n%+v", path, err)
+	}
+
+	return firstLine(string(contents)), nil
+}
+
+func firstLine(s string) string {
+	return strings.TrimRight(strings.SplitN(s, "\n", 2)[0], "\r")
+}
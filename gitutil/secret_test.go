@@ -0,0 +1,65 @@
+package gitutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestSecretEnvName(t *testing.T) {
+	cases := map[string]string{
+		"story.oauthtoken":     "GITCLI_OAUTHTOKEN",
+		"story.hosteddb.pass":  "GITCLI_HOSTEDDB_PASS",
+		"story.db.app-ro.pass": "GITCLI_DB_APP_RO_PASS",
+	}
+	for name, expected := range cases {
+		if result := SecretEnvName(name); result != expected {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` but got `%s`", expected, result))
+		}
+	}
+}
+
+func TestConfigSecretFromEnv(t *testing.T) {
+	os.Setenv("GITCLI_SECRETTEST", "from-env")
+	defer os.Unsetenv("GITCLI_SECRETTEST")
+
+	result, err := ConfigSecret("story.secrettest")
+	testutil.CheckFatal(t, err)
+	if result != "from-env" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `from-env` but got `%s`", result))
+	}
+}
+
+func TestSecretFromCommand(t *testing.T) {
+	result, err := secretFromCommand("printf 'from-cmd\\nsecond line'")
+	testutil.CheckFatal(t, err)
+	if result != "from-cmd" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `from-cmd` but got `%s`", result))
+	}
+}
+
+func TestSecretFromFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gitcli")
+	testutil.CheckFatal(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("from-file\n")
+	f.Close()
+
+	// readable by others, should be refused
+	err = os.Chmod(f.Name(), 0644)
+	testutil.CheckFatal(t, err)
+	if _, err = secretFromFile(f.Name()); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected error for file readable by others"))
+	}
+
+	err = os.Chmod(f.Name(), 0600)
+	testutil.CheckFatal(t, err)
+	result, err := secretFromFile(f.Name())
+	testutil.CheckFatal(t, err)
+	if result != "from-file" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `from-file` but got `%s`", result))
+	}
+}
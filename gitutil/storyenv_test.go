@@ -0,0 +1,77 @@
+package gitutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+func TestRenderStoryEnv(t *testing.T) {
+	env := StoryEnv{
+		Branch: "feature/ABC-12-login",
+		Issue:  "ABC-12",
+		Parent: "upstream/master",
+		Dbs:    []StoryEnvDb{{"default", "feature_abc_12_login_app"}, {"analytics", "feature_abc_12_login_events"}},
+	}
+
+	template := "STORY={branch} ({issue}) on {parent}\n" +
+		"DB={db}\nEVENTS_DB={db:analytics}\nMISSING_DB={db:billing}\nALL={dbs}\nHOME_DIR=${HOME}\n"
+	expected := "STORY=feature/ABC-12-login (ABC-12) on upstream/master\n" +
+		"DB=feature_abc_12_login_app\nEVENTS_DB=feature_abc_12_login_events\nMISSING_DB=\n" +
+		"ALL=feature_abc_12_login_app,feature_abc_12_login_events\nHOME_DIR=${HOME}\n"
+
+	if result := RenderStoryEnv(template, env); result != expected {
+		testutil.CheckFatal(t, fmt.Errorf("Expected\n%s\nbut got\n%s", expected, result))
+	}
+}
+
+func TestWriteStoryEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storyenv")
+	testutil.CheckFatal(t, err)
+	defer os.RemoveAll(dir)
+
+	template := filepath.Join(dir, "env.tmpl")
+	envFile := filepath.Join(dir, ".env.story")
+	testutil.CheckFatal(t, ioutil.WriteFile(template, []byte("STORY={branch}\n"), 0644))
+	testutil.CheckFatal(t, ioutil.WriteFile(envFile, []byte("STORY=old\n"), 0644))
+
+	testutil.CheckFatal(t, WriteStoryEnv(template, envFile, StoryEnv{Branch: "new"}))
+
+	content, err := ioutil.ReadFile(envFile)
+	testutil.CheckFatal(t, err)
+	if string(content) != "STORY=new\n" {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected env file `%s`", content))
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		testutil.CheckFatal(t, fmt.Errorf("Expected no temporary files left, got %d files", len(files)))
+	}
+}
+
+func TestStoryEnvPath(t *testing.T) {
+	workdir, err := ioutil.TempDir("", "gitcli")
+	testutil.CheckFatal(t, err)
+	defer os.RemoveAll(workdir)
+
+	outside, err := ioutil.TempDir("", "gitcli")
+	testutil.CheckFatal(t, err)
+	defer os.RemoveAll(outside)
+	testutil.CheckFatal(t, os.Symlink(outside, filepath.Join(workdir, "link")))
+
+	for _, path := range []string{".env.story", "config/.env", filepath.Join(workdir, "env")} {
+		if _, err := StoryEnvPath(workdir, path); err != nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be allowed: %v", path, err))
+		}
+	}
+
+	for _, path := range []string{"../../.bashrc", "/etc/profile", "link/.bashrc", filepath.Join(outside, "env")} {
+		if _, err := StoryEnvPath(workdir, path); err == nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to be refused", path))
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package issueutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// github uses GitHub issues, where labels mark issues being worked on
+type github struct {
+	Config
+}
+
+func (g *github) newRequest(method string, path string, body interface{}) (*http.Request, error) {
+
+	req, err := newRequest(method, strings.TrimRight(g.URL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", g.Token))
+	}
+
+	return req, nil
+}
+
+// repoPath is API path of repository in `owner/name` format, each part escaped
+func (g *github) repoPath() string {
+	path := "/repos"
+	for _, part := range strings.Split(g.Repo, "/") {
+		path += "/" + url.PathEscape(part)
+	}
+	return path
+}
+
+// GetIssue fetches issue of given number
+func (g *github) GetIssue(id string) (*Issue, error) {
+
+	req, err := g.newRequest("GET", fmt.Sprintf("%s/issues/%s", g.repoPath(), url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := do(req, &resp); err != nil {
+		return nil, fmt.Errorf("Unable to get GitHub issue `%s`\n%+v", id, err)
+	}
+
+	return &Issue{ID: strconv.Itoa(resp.Number), Title: resp.Title, URL: resp.HTMLURL}, nil
+}
+
+// StartProgress adds progress label to issue
+func (g *github) StartProgress(id string) error {
+
+	body := map[string][]string{"labels": {g.ProgressState}}
+	req, err := g.newRequest("POST", fmt.Sprintf("%s/issues/%s/labels", g.repoPath(), url.PathEscape(id)), body)
+	if err != nil {
+		return err
+	}
+
+	if err := do(req, nil); err != nil {
+		return fmt.Errorf("Unable to label GitHub issue `%s` as `%s`\n%+v", id, g.ProgressState, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package issueutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// gitlab uses GitLab issues, where labels mark issues being worked on
+type gitlab struct {
+	Config
+}
+
+func (g *gitlab) newRequest(method string, path string, body interface{}) (*http.Request, error) {
+
+	// project path is a single segment, with its slashes escaped
+	project := url.PathEscape(g.Repo)
+	req, err := newRequest(method, fmt.Sprintf("%s/projects/%s%s", strings.TrimRight(g.URL, "/"), project, path), body)
+	if err != nil {
+		return nil, err
+	}
+	if g.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", g.Token)
+	}
+
+	return req, nil
+}
+
+// GetIssue fetches issue of given iid
+func (g *gitlab) GetIssue(id string) (*Issue, error) {
+
+	req, err := g.newRequest("GET", "/issues/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		IID    int    `json:"iid"`
+		Title  string `json:"title"`
+		WebURL string `json:"web_url"`
+	}
+	if err := do(req, &resp); err != nil {
+		return nil, fmt.Errorf("Unable to get GitLab issue `%s`\n%+v", id, err)
+	}
+
+	return &Issue{ID: strconv.Itoa(resp.IID), Title: resp.Title, URL: resp.WebURL}, nil
+}
+
+// StartProgress adds progress label to issue
+func (g *gitlab) StartProgress(id string) error {
+
+	body := map[string]string{"add_labels": g.ProgressState}
+	req, err := g.newRequest("PUT", "/issues/"+url.PathEscape(id), body)
+	if err != nil {
+		return err
+	}
+
+	if err := do(req, nil); err != nil {
+		return fmt.Errorf("Unable to label GitLab issue `%s` as `%s`\n%+v", id, g.ProgressState, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package issueutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultProgressState is what issues are moved to when work on them starts
+const DefaultProgressState = "In Progress"
+
+// client is used for every request to issue trackers
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Issue is an issue in issue tracker
+type Issue struct {
+	ID    string
+	Title string
+	URL   string
+}
+
+// Provider talks to an issue tracker
+type Provider interface {
+	// GetIssue fetches issue of given id
+	GetIssue(id string) (*Issue, error)
+	// StartProgress marks issue of given id as being worked on
+	StartProgress(id string) error
+}
+
+// Config tells which issue tracker to use and how to access it
+type Config struct {
+	// Provider is one of `github`, `gitlab` or `jira`
+	Provider string
+	// URL is base URL of the API, defaulting to public GitHub and GitLab
+	URL string
+	// Repo is `owner/repo` for GitHub or project path for GitLab
+	Repo string
+	// User is who to authenticate as, used by Jira
+	User string
+	// Token is API token
+	Token string
+	// ProgressState is the Jira transition, or GitHub and GitLab label, for issues being worked on
+	ProgressState string
+}
+
+// NewProvider returns provider for issue tracker in config
+func NewProvider(config Config) (Provider, error) {
+
+	if config.ProgressState == "" {
+		config.ProgressState = DefaultProgressState
+	}
+
+	switch strings.ToLower(config.Provider) {
+	case "github":
+		if config.URL == "" {
+			config.URL = "https://api.github.com"
+		}
+		if config.Repo == "" {
+			return nil, fmt.Errorf("GitHub issues need repo in `owner/repo` form")
+		}
+		return &github{config}, nil
+	case "gitlab":
+		if config.URL == "" {
+			config.URL = "https://gitlab.com/api/v4"
+		}
+		if config.Repo == "" {
+			return nil, fmt.Errorf("GitLab issues need project path")
+		}
+		return &gitlab{config}, nil
+	case "jira":
+		if config.URL == "" {
+			return nil, fmt.Errorf("Jira issues need URL of Jira server")
+		}
+		return &jira{config}, nil
+	case "":
+		return nil, fmt.Errorf("Issue provider is not specified")
+	}
+
+	return nil, fmt.Errorf("Unknown issue provider `%s`. Use one of github, gitlab or jira", config.Provider)
+}
+
+// newRequest builds JSON request to given URL
+func newRequest(method string, url string, body interface{}) (*http.Request, error) {
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = strings.NewReader(string(data))
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+// do sends request and decodes JSON response into out, if given
+func do(req *http.Request, out interface{}) error {
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("`%s %s` failed with %s\n%s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
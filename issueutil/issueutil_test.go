@@ -0,0 +1,151 @@
+package issueutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kidonchu/gitcli/testutil"
+)
+
+// newTracker starts a stand-in issue tracker answering given routes,
+// and records bodies of requests it received by route
+func newTracker(t *testing.T, routes map[string]string) (*httptest.Server, map[string]string) {
+	received := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.EscapedPath()
+		body, _ := ioutil.ReadAll(r.Body)
+		received[route] = string(body)
+		received[route+" auth"] = r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")
+
+		response, ok := routes[route]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, response)
+	}))
+	return server, received
+}
+
+func checkIssue(t *testing.T, issue *Issue, id string, title string, url string) {
+	if issue.ID != id || issue.Title != title || issue.URL != url {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected issue %+v", issue))
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	server, received := newTracker(t, map[string]string{
+		"GET /repos/foo/bar/issues/12":         `{"number":12,"title":"Fix login","html_url":"https://github.com/foo/bar/issues/12"}`,
+		"POST /repos/foo/bar/issues/12/labels": `[]`,
+	})
+	defer server.Close()
+
+	provider, err := NewProvider(Config{Provider: "github", URL: server.URL, Repo: "foo/bar", Token: "secret"})
+	testutil.CheckFatal(t, err)
+
+	issue, err := provider.GetIssue("12")
+	testutil.CheckFatal(t, err)
+	checkIssue(t, issue, "12", "Fix login", "https://github.com/foo/bar/issues/12")
+	if received["GET /repos/foo/bar/issues/12 auth"] != "token secret" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected token to be sent"))
+	}
+
+	testutil.CheckFatal(t, provider.StartProgress("12"))
+	if received["POST /repos/foo/bar/issues/12/labels"] != `{"labels":["In Progress"]}` {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected label request %s", received["POST /repos/foo/bar/issues/12/labels"]))
+	}
+
+	if _, err := provider.GetIssue("13"); err == nil {
+		testutil.CheckFatal(t, fmt.Errorf("Expected missing issue to fail"))
+	}
+}
+
+func TestGitLab(t *testing.T) {
+	server, received := newTracker(t, map[string]string{
+		"GET /projects/group%2Fbar/issues/7": `{"iid":7,"title":"Add export","web_url":"https://gitlab.com/group/bar/issues/7"}`,
+		"PUT /projects/group%2Fbar/issues/7": `{}`,
+	})
+	defer server.Close()
+
+	provider, err := NewProvider(Config{Provider: "gitlab", URL: server.URL, Repo: "group/bar", Token: "secret", ProgressState: "Doing"})
+	testutil.CheckFatal(t, err)
+
+	issue, err := provider.GetIssue("7")
+	testutil.CheckFatal(t, err)
+	checkIssue(t, issue, "7", "Add export", "https://gitlab.com/group/bar/issues/7")
+	if received["GET /projects/group%2Fbar/issues/7 auth"] != "secret" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected private token to be sent"))
+	}
+
+	testutil.CheckFatal(t, provider.StartProgress("7"))
+	if received["PUT /projects/group%2Fbar/issues/7"] != `{"add_labels":"Doing"}` {
+		testutil.CheckFatal(t, fmt.Errorf("Unexpected label request %s", received["PUT /projects/group%2Fbar/issues/7"]))
+	}
+}
+
+func TestJira(t *testing.T) {
+	server, received := newTracker(t, map[string]string{
+		"GET /rest/api/2/issue/ABC-12":              `{"key":"ABC-12","fields":{"summary":"Redesign login page"}}`,
+		"GET /rest/api/2/issue/ABC-12/transitions":  `{"transitions":[{"id":"11","name":"Close","to":{"name":"Done"}},{"id":"21","name":"Start","to":{"name":"In Progress"}}]}`,
+		"POST /rest/api/2/issue/ABC-12/transitions": ``,
+	})
+	defer server.Close()
+
+	provider, err := NewProvider(Config{Provider: "jira", URL: server.URL, User: "kchu", Token: "secret"})
+	testutil.CheckFatal(t, err)
+
+	issue, err := provider.GetIssue("ABC-12")
+	testutil.CheckFatal(t, err)
+	checkIssue(t, issue, "ABC-12", "Redesign login page", server.URL+"/browse/ABC-12")
+
+	testutil.CheckFatal(t, provider.StartProgress("ABC-12"))
+	var body struct {
+		Transition struct {
+			ID string `json:"id"`
+		} `json:"transition"`
+	}
+	testutil.CheckFatal(t, json.Unmarshal([]byte(received["POST /rest/api/2/issue/ABC-12/transitions"]), &body))
+	if body.Transition.ID != "21" {
+		testutil.CheckFatal(t, fmt.Errorf("Expected transition `21` but got `%s`", body.Transition.ID))
+	}
+}
+
+func TestIssueIDEscaped(t *testing.T) {
+	server, _ := newTracker(t, map[string]string{
+		"GET /repos/foo/bar/issues/1%2F2%3Fx":        `{"number":1,"title":"GitHub"}`,
+		"GET /projects/group%2Fbar/issues/1%2F2%3Fx": `{"iid":1,"title":"GitLab"}`,
+		"GET /rest/api/2/issue/1%2F2%3Fx":            `{"key":"1/2?x","fields":{"summary":"Jira"}}`,
+	})
+	defer server.Close()
+
+	configs := []Config{
+		{Provider: "github", URL: server.URL, Repo: "foo/bar"},
+		{Provider: "gitlab", URL: server.URL, Repo: "group/bar"},
+		{Provider: "jira", URL: server.URL},
+	}
+	for _, config := range configs {
+		provider, err := NewProvider(config)
+		testutil.CheckFatal(t, err)
+		if _, err := provider.GetIssue("1/2?x"); err != nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected `%s` to escape issue id\n%+v", config.Provider, err))
+		}
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	invalid := []Config{
+		{},
+		{Provider: "trello"},
+		{Provider: "github"},
+		{Provider: "jira"},
+	}
+	for _, config := range invalid {
+		if _, err := NewProvider(config); err == nil {
+			testutil.CheckFatal(t, fmt.Errorf("Expected %+v to be rejected", config))
+		}
+	}
+}
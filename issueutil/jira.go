@@ -0,0 +1,97 @@
+package issueutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// jira uses Jira REST API, where issues are transitioned to progress state
+type jira struct {
+	Config
+}
+
+func (j *jira) newRequest(method string, path string, body interface{}) (*http.Request, error) {
+
+	req, err := newRequest(method, strings.TrimRight(j.URL, "/")+"/rest/api/2"+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Jira cloud takes user with API token, Jira server takes personal access token
+	if j.User != "" {
+		req.SetBasicAuth(j.User, j.Token)
+	} else if j.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", j.Token))
+	}
+
+	return req, nil
+}
+
+// GetIssue fetches issue of given key
+func (j *jira) GetIssue(id string) (*Issue, error) {
+
+	req, err := j.newRequest("GET", fmt.Sprintf("/issue/%s?fields=summary", url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+		} `json:"fields"`
+	}
+	if err := do(req, &resp); err != nil {
+		return nil, fmt.Errorf("Unable to get Jira issue `%s`\n%+v", id, err)
+	}
+
+	link := fmt.Sprintf("%s/browse/%s", strings.TrimRight(j.URL, "/"), url.PathEscape(resp.Key))
+	return &Issue{ID: resp.Key, Title: resp.Fields.Summary, URL: link}, nil
+}
+
+// StartProgress moves issue with the transition named, or leading to, progress state
+func (j *jira) StartProgress(id string) error {
+
+	req, err := j.newRequest("GET", fmt.Sprintf("/issue/%s/transitions", url.PathEscape(id)), nil)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := do(req, &resp); err != nil {
+		return fmt.Errorf("Unable to get transitions of Jira issue `%s`\n%+v", id, err)
+	}
+
+	transitionID := ""
+	for _, transition := range resp.Transitions {
+		if strings.EqualFold(transition.Name, j.ProgressState) || strings.EqualFold(transition.To.Name, j.ProgressState) {
+			transitionID = transition.ID
+			break
+		}
+	}
+	if transitionID == "" {
+		return fmt.Errorf("Jira issue `%s` cannot be moved to `%s`", id, j.ProgressState)
+	}
+
+	body := map[string]map[string]string{"transition": {"id": transitionID}}
+	req, err = j.newRequest("POST", fmt.Sprintf("/issue/%s/transitions", url.PathEscape(id)), body)
+	if err != nil {
+		return err
+	}
+
+	if err := do(req, nil); err != nil {
+		return fmt.Errorf("Unable to move Jira issue `%s` to `%s`\n%+v", id, j.ProgressState, err)
+	}
+
+	return nil
+}